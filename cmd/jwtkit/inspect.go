@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// millisecondThreshold separates millisecond timestamps, which the legacy format uses, from
+// seconds, 1e11 seconds is far beyond year 5000 while 1e11 milliseconds is in 1973.
+const millisecondThreshold = 1e11
+
+func inspect(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	token, err := readToken(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	jwtParts := strings.Split(token, ".")
+	if len(jwtParts) != 3 {
+		return &invalidTokenError{fmt.Errorf("token must have 3 parts got %d", len(jwtParts))}
+	}
+
+	fmt.Fprintln(stdout, "header:")
+	_, err = printSegment(stdout, jwtParts[0])
+	if err != nil {
+		return &invalidTokenError{fmt.Errorf("header: %v", err)}
+	}
+	fmt.Fprintln(stdout, "payload:")
+	payload, err := printSegment(stdout, jwtParts[1])
+	if err != nil {
+		return &invalidTokenError{fmt.Errorf("payload: %v", err)}
+	}
+
+	now := time.Now()
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		timestamp, ok := payload[name].(json.Number)
+		if !ok {
+			continue
+		}
+		value, err := timestamp.Int64()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", name, formatTimestamp(value, now))
+	}
+	fmt.Fprintln(stdout, "signature not verified")
+
+	return nil
+}
+
+// printSegment pretty prints a JSON segment, padded and unpadded base64url are both accepted
+// so legacy tokens can be inspected as well.
+func printSegment(w io.Writer, segment string) (map[string]interface{}, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(decoded))
+	decoder.UseNumber()
+	err = decoder.Decode(&fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	err = json.Indent(&indented, decoded, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w, indented.String())
+
+	return fields, nil
+}
+
+func formatTimestamp(value int64, now time.Time) string {
+	var t time.Time
+	if value >= millisecondThreshold {
+		t = time.UnixMilli(value)
+	} else {
+		t = time.Unix(value, 0)
+	}
+
+	relative := t.Sub(now).Round(time.Second)
+	if relative < 0 {
+		return fmt.Sprintf("%s (%s ago)", t.UTC().Format(time.RFC3339), -relative)
+	}
+	return fmt.Sprintf("%s (in %s)", t.UTC().Format(time.RFC3339), relative)
+}
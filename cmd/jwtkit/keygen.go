@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func keygen(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("keygen", stderr)
+	alg := fs.String("alg", jwtkit.AlgorithmES256, "ES256, ES384, ES512, RS256, RS384, RS512, PS256, EdDSA, HS256, HS384 or HS512")
+	privatePath := fs.String("private", "private.pem", "private key file, the raw secret for HS*")
+	publicPath := fs.String("public", "public.pem", "public key file, not written for HS*")
+	force := fs.Bool("force", false, "overwrite existing files")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := jwtkit.GenerateKey(*alg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	files := map[string][]byte{}
+	if secret, ok := privateKey.([]byte); ok {
+		files[*privatePath] = secret
+	} else {
+		files[*privatePath], err = jwtkit.MarshalPrivateKeyPEM(privateKey)
+		if err != nil {
+			return err
+		}
+		files[*publicPath], err = jwtkit.MarshalPublicKeyPEM(privateKey.(crypto.Signer).Public())
+		if err != nil {
+			return err
+		}
+	}
+
+	if !*force {
+		for path := range files {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, use -force to overwrite it", path)
+			}
+		}
+	}
+
+	// every key goes into a temporary file first and is only moved into place once all of them are
+	// written, so a failed write never replaces a private key without its public key
+	paths := make([]string, 0, len(files))
+	tmps := make(map[string]string, len(files))
+	defer func() {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+	for _, path := range []string{*privatePath, *publicPath} {
+		data, ok := files[path]
+		if !ok {
+			continue
+		}
+		tmp, err := writeTempFile(path, data)
+		if err != nil {
+			return err
+		}
+		tmps[path] = tmp
+		paths = append(paths, path)
+	}
+
+	for _, path := range paths {
+		err = moveIntoPlace(tmps[path], path, *force)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stdout, "%s key written to %s\n", *alg, strings.Join(paths, ", "))
+
+	return nil
+}
+
+// writeTempFile writes data into a temporary file next to path, so readers never see a partially
+// written key once moveIntoPlace moves it.
+func writeTempFile(path string, data []byte) (string, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return "", err
+	}
+
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// moveIntoPlace renames tmp to path, without overwrite it fails if path exists.
+func moveIntoPlace(tmp string, path string, overwrite bool) error {
+	if overwrite {
+		return os.Rename(tmp, path)
+	}
+	err := os.Link(tmp, path)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", path)
+	}
+	return err
+}
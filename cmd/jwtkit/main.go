@@ -0,0 +1,125 @@
+// Command jwtkit generates keys and signs, verifies and inspects tokens.
+//
+//	jwtkit keygen  [-alg ES256] [-private private.pem] [-public public.pem] [-force]
+//	jwtkit sign    -key private.pem [-alg ES256] [-kid id] [-iss issuer] [-aud audience] [-exp 1h] [-millis] < claims.json
+//	jwtkit verify  (-key public.pem | -jwks jwks.json) [-iss issuer] [-aud audience] [-leeway 0s] [-millis] [token]
+//	jwtkit inspect [token]
+//
+// The token is read from stdin when it is not given as an argument.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+var errUsage = errors.New("usage")
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"keygen", "generate a key pair, or an HMAC secret, into files with 0600 permissions", keygen},
+	{"sign", "sign the JSON claims read from stdin", sign},
+	{"verify", "verify the signature and registered claims of a token", verify},
+	{"inspect", "print the header and payload of a token without verifying it", inspect},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdin, stdout, stderr)
+		if errors.Is(err, errUsage) {
+			if err != errUsage {
+				fmt.Fprintf(stderr, "jwtkit %s: %v\n", cmd.name, err)
+			}
+			return exitUsage
+		}
+		var invalidErr *invalidTokenError
+		if errors.As(err, &invalidErr) {
+			fmt.Fprintf(stderr, "invalid: %v\n", invalidErr.err)
+			return exitInvalid
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "jwtkit %s: %v\n", cmd.name, err)
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	printUsage(stderr)
+	return exitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: jwtkit <command> [flags]")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// invalidTokenError separates a token failing verification, exit code 1, from misuse of the tool, exit code 2.
+type invalidTokenError struct {
+	err error
+}
+
+func (ite *invalidTokenError) Error() string { return ite.err.Error() }
+
+func (ite *invalidTokenError) Unwrap() error { return ite.err }
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("jwtkit "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+func readToken(args []string, stdin io.Reader) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("%w: expected at most one token", errUsage)
+	}
+	if len(args) == 1 {
+		return strings.TrimSpace(args[0]), nil
+	}
+	data, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.New("no token given")
+	}
+	return token, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestKeygenSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+
+	code, _, stderr := runCommand("", "keygen", "-alg", "EdDSA", "-private", privatePath, "-public", publicPath)
+	if code != exitOK {
+		t.Fatalf("keygen expected exit %d got: %d %s", exitOK, code, stderr)
+	}
+	info, err := os.Stat(privatePath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected 0600 private key got: %v %v", info, err)
+	}
+	code, _, _ = runCommand("", "keygen", "-private", privatePath, "-public", publicPath)
+	if code != exitUsage {
+		t.Fatalf("keygen expected to refuse overwriting got: %d", code)
+	}
+	otherPrivatePath := filepath.Join(dir, "other.pem")
+	code, _, _ = runCommand("", "keygen", "-private", otherPrivatePath, "-public", filepath.Join(dir, "missing", "public.pem"))
+	if _, err := os.Stat(otherPrivatePath); code == exitOK || !os.IsNotExist(err) {
+		t.Fatalf("expected a failed keygen to leave no private key got: %d %v", code, err)
+	}
+	previousKey, err := ioutil.ReadFile(privatePath)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	code, _, _ = runCommand("", "keygen", "-force", "-private", privatePath, "-public", filepath.Join(dir, "missing", "public.pem"))
+	if currentKey, err := ioutil.ReadFile(privatePath); code == exitOK || err != nil || !bytes.Equal(currentKey, previousKey) {
+		t.Fatalf("expected a failed forced keygen to keep the previous private key got: %d %v", code, err)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp*")); len(tmps) != 0 {
+		t.Fatalf("expected no temporary files left got: %v", tmps)
+	}
+
+	code, token, stderr := runCommand(`{"sub":"subject","role":"admin"}`, "sign", "-key", privatePath, "-iss", "issuer")
+	if code != exitOK {
+		t.Fatalf("sign expected exit %d got: %d %s", exitOK, code, stderr)
+	}
+	code, millisToken, stderr := runCommand(`{"sub":"subject"}`, "sign", "-key", privatePath, "-millis")
+	if code != exitOK {
+		t.Fatalf("sign -millis expected exit %d got: %d %s", exitOK, code, stderr)
+	}
+	signer, err := jwtkit.ProviderSigner(&jwtkit.FileKeyProvider{PrivateKeyPath: privatePath}, "")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	libraryToken, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		stdin    string
+		args     []string
+		expected int
+	}{
+		{"valid", token, []string{"verify", "-key", publicPath, "-iss", "issuer"}, exitOK},
+		{"library token", string(libraryToken), []string{"verify", "-key", publicPath, "-iss", "issuer"}, exitOK},
+		{"millis", millisToken, []string{"verify", "-key", publicPath, "-millis"}, exitOK},
+		{"millis token as seconds", millisToken, []string{"verify", "-key", publicPath}, exitInvalid},
+		{"seconds token as millis", token, []string{"verify", "-key", publicPath, "-millis"}, exitInvalid},
+		{"issuer mismatch", token, []string{"verify", "-key", publicPath, "-iss", "other"}, exitInvalid},
+		{"tampered", token[:len(token)-3] + "AAA", []string{"verify", "-key", publicPath}, exitInvalid},
+		{"no key", token, []string{"verify"}, exitUsage},
+		{"inspect", token, []string{"inspect"}, exitOK},
+		{"inspect malformed", "a.b", []string{"inspect"}, exitInvalid},
+	}
+
+	for _, tc := range testCases {
+		code, stdout, stderr := runCommand(tc.stdin, tc.args...)
+		if code != tc.expected {
+			t.Fatalf("%s expected exit %d got: %d %s%s", tc.name, tc.expected, code, stdout, stderr)
+		}
+	}
+
+	_, stdout, _ := runCommand(token, "inspect")
+	if !strings.Contains(stdout, `"role": "admin"`) || !strings.Contains(stdout, "exp: ") {
+		t.Fatalf("unexpected inspect output: %s", stdout)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func sign(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("sign", stderr)
+	keyPath := fs.String("key", "", "private key PEM file, the raw secret for HS*")
+	alg := fs.String("alg", "", "signing algorithm, taken from the key when empty")
+	kid := fs.String("kid", "", "kid header")
+	iss := fs.String("iss", "", "iss claim unless given on stdin")
+	aud := fs.String("aud", "", "aud claim unless given on stdin")
+	exp := fs.Duration("exp", time.Hour, "lifetime used for exp unless given on stdin, 0 for none")
+	millis := fs.Bool("millis", false, "legacy milliseconds instead of RFC 7519 NumericDate seconds")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		return fmt.Errorf("%w: -key is required", errUsage)
+	}
+
+	key, err := loadKey(*keyPath, true)
+	if err != nil {
+		return err
+	}
+	if *alg == "" {
+		*alg, err = jwtkit.AlgorithmFromKey(key)
+		if err != nil {
+			return err
+		}
+	}
+	signer, err := jwtkit.NewSigner(*alg, key)
+	if err != nil {
+		return err
+	}
+	if *kid != "" {
+		signer = jwtkit.WithKeyID(signer, *kid)
+	}
+
+	rc, claims, err := readClaims(stdin)
+	if err != nil {
+		return err
+	}
+
+	issuer := &jwtkit.Issuer{
+		Signer:     signer,
+		Issuer:     *iss,
+		Audience:   jwtkit.NewAudience(*aud),
+		Expiration: jwtkit.JWTExpiration(exp.Milliseconds()),
+		TimeUnit:   timeUnit(*millis),
+	}
+	token, err := issuer.IssueRegistered(rc, claims)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, token)
+
+	return nil
+}
+
+func timeUnit(millis bool) jwtkit.TimeUnit {
+	if millis {
+		return jwtkit.Milliseconds
+	}
+	return jwtkit.Seconds
+}
+
+// readClaims splits the JSON object on stdin into registered and custom claims, numbers of custom
+// claims are kept as written.
+func readClaims(stdin io.Reader) (*jwtkit.RegisteredClaims, map[string]interface{}, error) {
+	data, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}")
+	}
+
+	var rc jwtkit.RegisteredClaims
+	err = json.Unmarshal(data, &rc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("claims must be a JSON object: %v", err)
+	}
+
+	var claims map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&claims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("claims must be a JSON object: %v", err)
+	}
+	for _, name := range jwtkit.RegisteredClaimNames() {
+		delete(claims, name)
+	}
+
+	return &rc, claims, nil
+}
+
+// loadKey reads a PEM key, a file without any PEM block is an HMAC secret. The key type is decided
+// by the file and never by the token, so a public key can not be turned into an HMAC secret.
+// Verification accepts a private key file too.
+func loadKey(path string, private bool) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block == nil {
+		return data, nil
+	}
+
+	if !private {
+		publicKey, err := jwtkit.ParsePublicKeyPEM(data)
+		if err == nil {
+			return publicKey, nil
+		}
+	}
+	privateKey, err := jwtkit.ParsePrivateKeyPEM(data)
+	if err != nil {
+		if !private {
+			return nil, errors.New("no public or private key found in " + path)
+		}
+		return nil, err
+	}
+	return privateKey, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func verify(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("verify", stderr)
+	keyPath := fs.String("key", "", "public or private key PEM file, the raw secret for HS*")
+	jwksPath := fs.String("jwks", "", "JWKS file, the key is picked by the kid header")
+	iss := fs.String("iss", "", "required iss claim")
+	aud := fs.String("aud", "", "required aud claim")
+	leeway := fs.Duration("leeway", 0, "clock skew allowed for exp, nbf and iat")
+	requireExp := fs.Bool("require-exp", false, "reject tokens without exp")
+	millis := fs.Bool("millis", false, "legacy milliseconds instead of RFC 7519 NumericDate seconds")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*keyPath == "") == (*jwksPath == "") {
+		return fmt.Errorf("%w: exactly one of -key and -jwks is required", errUsage)
+	}
+	token, err := readToken(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	header, err := peekHeader(token)
+	if err != nil {
+		return &invalidTokenError{err}
+	}
+
+	validator := &jwtkit.Validator{TimeUnit: timeUnit(*millis), Leeway: *leeway, Issuer: *iss, Audience: *aud, RequireExpiration: *requireExp}
+	var verifier jwtkit.TokenVerifier
+	switch {
+	case header.Algorithm == jwtkit.AlgorithmLegacyECDSA && *keyPath != "":
+		validator.TimeUnit = jwtkit.Milliseconds
+		verifier = &jwtkit.LegacyJWTVerifier{ECDSA: &jwtkit.ECDSA{PublicKeyPath: *keyPath}, Validator: validator}
+	case header.Algorithm == jwtkit.AlgorithmLegacyECDSA:
+		return fmt.Errorf("%w: legacy tokens can only be verified with -key", errUsage)
+	case *jwksPath != "":
+		jwksJSON, err := ioutil.ReadFile(*jwksPath)
+		if err != nil {
+			return err
+		}
+		ks, err := jwtkit.ParseJWKS(jwksJSON)
+		if err != nil {
+			return err
+		}
+		verifier = &jwtkit.JWTVerifier{Resolver: ks, Validator: validator}
+	default:
+		key, err := loadKey(*keyPath, false)
+		if err != nil {
+			return err
+		}
+		verifier = &jwtkit.JWTVerifier{Resolver: jwtkit.KeyVerifierResolver(key), Validator: validator}
+	}
+
+	jwt, err := verifier.VerifyToken(jwtkit.JWTString(token))
+	if err != nil {
+		return &invalidTokenError{err}
+	}
+	fmt.Fprintf(stdout, "valid %s token", jwt.Header.Algorithm)
+	if jwt.Payload.Subject != "" {
+		fmt.Fprintf(stdout, " for %s", jwt.Payload.Subject)
+	}
+	if jwt.Payload.ExpiredAt != 0 {
+		fmt.Fprintf(stdout, ", expires %s", formatTimestamp(jwt.Payload.ExpiredAt, time.Now()))
+	}
+	fmt.Fprintln(stdout)
+
+	return nil
+}
+
+func peekHeader(token string) (*jwtkit.Header, error) {
+	if strings.Count(token, ".") != 2 {
+		return nil, fmt.Errorf("token must have 3 parts")
+	}
+	jwt, err := jwtkit.GetJWT(jwtkit.JWTString(token))
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Header, nil
+}
@@ -13,9 +13,23 @@ import (
 const (
 	FlowErrHttpHeaderParamNotExists uint = iota
 	FlowErrURLQueryNotExists
+	// DetailedErrLastIota is where the constants of packages using errorkit start, it never moves
 	DetailedErrLastIota
 )
 
+// toolkitErrBase starts the constants added after DetailedErrLastIota was published, far above it
+// so they never collide with constants built from DetailedErrLastIota.
+const toolkitErrBase uint = 1 << 16
+
+const (
+	FlowErrUnauthenticated uint = toolkitErrBase + iota
+	FlowErrInsufficientScope
+	FlowErrInsufficientRole
+	FlowErrClaimMismatch
+	FlowErrForbidden
+	NonFlowErrPanic
+)
+
 type ErrDescGenerator interface {
 	GenerateDesc(uint, ...string) string
 }
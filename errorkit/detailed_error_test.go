@@ -94,3 +94,15 @@ func TestDetailedError(t *testing.T) {
 	detailedErr2 := NewDetailedError(true, callTraceFunc, nil, Err1stLayerInvalidType, DescGeneration("detailed internal error 2"))
 	IsNotNilThenLog(detailedErr2)
 }
+
+func TestDetailedErrLastIota(t *testing.T) {
+	// constants of packages using errorkit are built from it, moving it changes serialised ErrDescConst
+	if DetailedErrLastIota != 2 {
+		t.Fatalf("expected DetailedErrLastIota to stay 2 got: %d", DetailedErrLastIota)
+	}
+	for _, errDescConst := range []uint{FlowErrUnauthenticated, FlowErrForbidden, NonFlowErrPanic} {
+		if errDescConst < toolkitErrBase {
+			t.Fatalf("expected %d at or above %d", errDescConst, toolkitErrBase)
+		}
+	}
+}
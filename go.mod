@@ -11,3 +11,8 @@ retract (
 )
 
 require github.com/google/uuid v1.3.0
+
+require (
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sys v0.15.0 // indirect
+)
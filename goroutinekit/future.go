@@ -0,0 +1,151 @@
+package goroutinekit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrJobAbandoned = errors.New("job abandoned by the worker pool")
+	ErrJobPanicked  = errors.New("job panicked")
+)
+
+// Future is the result of a job submitted with Submit, it is resolved exactly once.
+type Future[T any] struct {
+	done   chan struct{}
+	once   sync.Once
+	result T
+	err    error
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+func (f *Future[T]) resolve(result T, err error) {
+	f.once.Do(func() {
+		f.result, f.err = result, err
+		close(f.done)
+	})
+}
+
+// Done is closed once the result is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result, ending ctx only stops waiting and never the job itself.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Submit queues fn on wp, blocking while the queue is full until ctx ends. fn gets a context that
+// ends with ctx or with the pool. A job whose ctx ended before it started is not run, its Future
+// holds ctx.Err(), a job the pool abandons holds ErrJobAbandoned and a panicking job holds an
+// *errorkit.DetailedError matching ErrJobPanicked.
+func Submit[T any](ctx context.Context, wp *WorkerPool, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	future := newFuture[T]()
+	var zero T
+
+	err := wp.submit(task{
+		run: func() {
+			if err := ctx.Err(); err != nil {
+				future.resolve(zero, err)
+				return
+			}
+			jobCtx, cancel := wp.jobContext(ctx)
+			defer cancel()
+
+			result, err := fn(jobCtx)
+			future.resolve(result, err)
+		},
+		abandon: func() { future.resolve(zero, ErrJobAbandoned) },
+		failed:  func(pe *PanicError) { future.resolve(zero, pe.DetailedError()) },
+	}, ctx, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return future, nil
+}
+
+// jobContext ends with ctx or with the pool, whichever ends first.
+func (wp *WorkerPool) jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-wp.ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	return jobCtx, func() {
+		close(stop)
+		cancel()
+	}
+}
+
+// Map runs fn for every item on wp and returns the results in the order of items. The first error
+// cancels the context of the other calls and is returned. Calling Map from a job of the same pool
+// can deadlock once every worker waits for jobs queued behind it.
+func Map[T any, R any](ctx context.Context, wp *WorkerPool, items []T, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var firstErr error
+	var errOnce sync.Once
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	futures := make([]*Future[R], 0, len(items))
+	for i := range items {
+		item := items[i]
+		future, err := Submit(ctx, wp, func(ctx context.Context) (R, error) {
+			result, err := fn(ctx, item)
+			if err != nil {
+				fail(err)
+			}
+			return result, err
+		})
+		if err != nil {
+			fail(err)
+			break
+		}
+		futures = append(futures, future)
+	}
+
+	results := make([]R, len(items))
+	for i, future := range futures {
+		// futures resolve even when cancelled, so waiting without a context can not hang
+		result, err := future.Await(context.Background())
+		if err != nil {
+			fail(err)
+		}
+		results[i] = result
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// ForEach is Map for calls without results.
+func ForEach[T any](ctx context.Context, wp *WorkerPool, items []T, fn func(ctx context.Context, item T) error) error {
+	_, err := Map(ctx, wp, items, func(ctx context.Context, item T) (struct{}, error) {
+		return struct{}{}, fn(ctx, item)
+	})
+	return err
+}
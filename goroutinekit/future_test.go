@@ -0,0 +1,104 @@
+package goroutinekit_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/goroutinekit"
+)
+
+func TestSubmitFuture(t *testing.T) {
+	wp := goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{MaxWorkers: 1, QueueSize: 4})
+	errFailed := errors.New("failed")
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		fn       func(ctx context.Context) (int, error)
+		expected int
+		err      error
+	}{
+		{"result", func(ctx context.Context) (int, error) { return 42, nil }, 42, nil},
+		{"error", func(ctx context.Context) (int, error) { return 0, errFailed }, 0, errFailed},
+		{"panic", func(ctx context.Context) (int, error) { panic("boom") }, 0, goroutinekit.ErrJobPanicked},
+	}
+	for _, tc := range testCases {
+		future, err := goroutinekit.Submit(ctx, wp, tc.fn)
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		result, err := future.Await(ctx)
+		if result != tc.expected || !errors.Is(err, tc.err) {
+			t.Fatalf("%s expected %d %v got: %d %v", tc.name, tc.expected, tc.err, result, err)
+		}
+	}
+
+	started := make(chan struct{})
+	running, err := goroutinekit.Submit(ctx, wp, func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	<-started
+	queued, err := goroutinekit.Submit(ctx, wp, func(ctx context.Context) (int, error) { return 1, nil })
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	awaitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err = queued.Await(awaitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v got: %v", context.DeadlineExceeded, err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	wp.Shutdown(shutdownCtx)
+	if _, err = running.Await(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the running job to see the pool cancelled got: %v", err)
+	}
+	if _, err = queued.Await(ctx); !errors.Is(err, goroutinekit.ErrJobAbandoned) {
+		t.Fatalf("expected %v got: %v", goroutinekit.ErrJobAbandoned, err)
+	}
+}
+
+func TestMap(t *testing.T) {
+	wp := goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{MaxWorkers: 4})
+	defer wp.Shutdown(context.Background())
+	items := []int{5, 4, 3, 2, 1}
+
+	results, err := goroutinekit.Map(context.Background(), wp, items, func(ctx context.Context, item int) (string, error) {
+		time.Sleep(time.Duration(item) * time.Millisecond)
+		return strconv.Itoa(item), nil
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	for i := range items {
+		if results[i] != strconv.Itoa(items[i]) {
+			t.Fatalf("expected results in order got: %v", results)
+		}
+	}
+
+	errFailed := errors.New("failed")
+	start := time.Now()
+	err = goroutinekit.ForEach(context.Background(), wp, []int{1, 2, 3, 4, 5, 6, 7, 8}, func(ctx context.Context, item int) error {
+		if item == 1 {
+			return errFailed
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, errFailed) || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected the first error to cancel the rest got: %v after %s", err, time.Since(start))
+	}
+}
@@ -1,19 +1,8 @@
 package goroutinekit
 
-import (
-	"log"
-	"runtime"
-)
+import "fmt"
 
+// Do runs fn in a new goroutine, a panic is recovered and handed to the handler set with SetPanicHandler.
 func Do(fn func()) {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				stack := make([]byte, 1024*8)
-				stack = stack[:runtime.Stack(stack, false)]
-				log.Printf("PANIC: %s\n%s\n", r, stack)
-			}
-		}()
-		fn()
-	}()
+	go runRecovered(fn, nil, fmt.Sprintf("%s#Do", callTraceFilePanic))
 }
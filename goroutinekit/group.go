@@ -0,0 +1,158 @@
+package goroutinekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// JoinedError holds every error returned to a Group with CollectErrors set.
+type JoinedError struct {
+	Errs []error
+}
+
+func (je *JoinedError) Error() string {
+	msgs := make([]string, len(je.Errs))
+	for i, err := range je.Errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (je *JoinedError) Unwrap() []error { return je.Errs }
+
+// Is matches when any of the joined errors matches target.
+func (je *JoinedError) Is(target error) bool {
+	for _, err := range je.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the joined errors that matches target.
+func (je *JoinedError) As(target interface{}) bool {
+	for _, err := range je.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Group runs functions in their own goroutine and waits for them. The zero value has no limit and
+// no context, use WithContext for a context that is cancelled on the first error.
+type Group struct {
+	// CollectErrors makes Wait return every error as *JoinedError instead of only the first one,
+	// the context is then left running until Wait returns
+	CollectErrors bool
+	// PanicHandler is told about panicking functions, the one set with SetPanicHandler when nil
+	PanicHandler PanicHandler
+
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	sem     chan struct{}
+	errOnce sync.Once
+	mu      sync.Mutex
+	err     error
+	errs    []error
+}
+
+// WithContext returns a Group and a context derived from ctx that is cancelled on the first error
+// or when Wait returns.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// SetLimit caps the number of running functions to n, a negative n removes the cap. It must not be
+// called while functions are running.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("goroutinekit: modify limit while %d functions are running", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs fn in a new goroutine, blocking while the limit is reached. A panic in fn is handed to the
+// panic handler and recorded as an *errorkit.DetailedError matching ErrJobPanicked.
+func (g *Group) Go(fn func() error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(fn)
+}
+
+// TryGo is Go that returns false instead of blocking when the limit is reached.
+func (g *Group) TryGo(fn func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(fn)
+	return true
+}
+
+func (g *Group) start(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+
+		var err error
+		pe := runRecovered(func() { err = fn() }, g.PanicHandler, fmt.Sprintf("%s#Group", callTraceFilePanic))
+		if pe != nil {
+			err = pe.DetailedError()
+		}
+		if err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+func (g *Group) fail(err error) {
+	if g.CollectErrors {
+		g.mu.Lock()
+		g.errs = append(g.errs, err)
+		g.mu.Unlock()
+		return
+	}
+	g.errOnce.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel()
+		}
+	})
+}
+
+// Wait waits for every function to return and returns the first error, or every error as
+// *JoinedError with CollectErrors set.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	if g.CollectErrors {
+		if len(g.errs) == 0 {
+			return nil
+		}
+		return &JoinedError{Errs: g.errs}
+	}
+	return g.err
+}
@@ -0,0 +1,65 @@
+package goroutinekit_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/goroutinekit"
+)
+
+func TestGroup(t *testing.T) {
+	errFailed := errors.New("failed")
+	g, ctx := goroutinekit.WithContext(context.Background())
+	g.SetLimit(2)
+	var running, maxRunning int32
+	for i := 0; i < 6; i++ {
+		i := i
+		g.Go(func() error {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			if i == 3 {
+				return errFailed
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Millisecond):
+				return nil
+			}
+		})
+	}
+	if err := g.Wait(); !errors.Is(err, errFailed) || ctx.Err() == nil {
+		t.Fatalf("expected %v to cancel the context got: %v %v", errFailed, err, ctx.Err())
+	}
+	if maxRunning > 2 {
+		t.Fatalf("expected at most 2 running functions got: %d", maxRunning)
+	}
+
+	release := make(chan struct{})
+	g = &goroutinekit.Group{CollectErrors: true, PanicHandler: &goroutinekit.PanicCounter{}}
+	g.SetLimit(1)
+	g.Go(func() error {
+		<-release
+		return errFailed
+	})
+	if g.TryGo(func() error { return nil }) {
+		t.Fatalf("expected TryGo to fail while the limit is reached")
+	}
+	close(release)
+	g.Go(func() error { panic("boom") })
+	g.Go(func() error { return nil })
+	err := g.Wait()
+	var joinedErr *goroutinekit.JoinedError
+	if !errors.As(err, &joinedErr) || len(joinedErr.Errs) != 2 || !errors.Is(err, errFailed) || !errors.Is(err, goroutinekit.ErrJobPanicked) {
+		t.Fatalf("expected every error to be collected got: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package goroutinekit
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/ilhammhdd/go-toolkit/errorkit"
+)
+
+const callTraceFilePanic = "/goroutinekit/panic.go"
+
+// PanicError is a recovered panic with the full stack of the goroutine that panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+	// CallTrace tells where it was recovered, e.g. "/goroutinekit/panic.go#Do"
+	CallTrace string
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", pe.Value, pe.Stack)
+}
+
+// Is makes every PanicError match ErrJobPanicked.
+func (pe *PanicError) Is(target error) bool { return target == ErrJobPanicked }
+
+// Unwrap returns the panic value when it is an error, e.g. a runtime.Error.
+func (pe *PanicError) Unwrap() error {
+	err, _ := pe.Value.(error)
+	return err
+}
+
+var PanicErrDescGenerator errorkit.ErrDescGenerator = errorkit.ErrDescGeneratorFunc(func(errDescConst uint, args ...string) string {
+	if len(args) == 0 {
+		return "panic"
+	}
+	return fmt.Sprintf("panic: %s", args[0])
+})
+
+// DetailedError converts the panic into a non flow errorkit.DetailedError wrapping pe.
+func (pe *PanicError) DetailedError() *errorkit.DetailedError {
+	return errorkit.NewDetailedError(false, pe.CallTrace, pe, errorkit.NonFlowErrPanic, PanicErrDescGenerator, fmt.Sprint(pe.Value))
+}
+
+// PanicHandler is told about every panic recovered by Do, WorkerPool and Group.
+type PanicHandler interface {
+	HandlePanic(pe *PanicError)
+}
+
+type PanicHandlerFunc func(pe *PanicError)
+
+func (phf PanicHandlerFunc) HandlePanic(pe *PanicError) { phf(pe) }
+
+// LogPanicHandler writes the panic and its stack to the standard logger, it is the default.
+var LogPanicHandler PanicHandler = PanicHandlerFunc(func(pe *PanicError) {
+	log.Printf("PANIC: %v\n%s\n", pe.Value, pe.Stack)
+})
+
+// PanicHandlers calls every handler in order.
+func PanicHandlers(handlers ...PanicHandler) PanicHandler {
+	return PanicHandlerFunc(func(pe *PanicError) {
+		for _, handler := range handlers {
+			handler.HandlePanic(pe)
+		}
+	})
+}
+
+// ErrorChannelPanicHandler sends every panic as *errorkit.DetailedError on errs, the send blocks
+// the recovering goroutine so errs must be drained.
+func ErrorChannelPanicHandler(errs chan<- error) PanicHandler {
+	return PanicHandlerFunc(func(pe *PanicError) {
+		errs <- pe.DetailedError()
+	})
+}
+
+// RepanicHandler panics again with the PanicError, meant for tests that must not miss a panic.
+var RepanicHandler PanicHandler = PanicHandlerFunc(func(pe *PanicError) {
+	panic(pe)
+})
+
+// PanicCounter counts panics, e.g. to export them as a metric.
+type PanicCounter struct {
+	count uint64
+}
+
+func (pc *PanicCounter) HandlePanic(pe *PanicError) {
+	atomic.AddUint64(&pc.count, 1)
+}
+
+func (pc *PanicCounter) Count() uint64 {
+	return atomic.LoadUint64(&pc.count)
+}
+
+type panicHandlerHolder struct {
+	handler PanicHandler
+}
+
+var defaultPanicHandler atomic.Value
+
+func init() {
+	defaultPanicHandler.Store(panicHandlerHolder{LogPanicHandler})
+}
+
+// SetPanicHandler replaces the handler used where no other is configured and returns the previous one.
+func SetPanicHandler(handler PanicHandler) PanicHandler {
+	if handler == nil {
+		handler = LogPanicHandler
+	}
+	return defaultPanicHandler.Swap(panicHandlerHolder{handler}).(panicHandlerHolder).handler
+}
+
+func currentPanicHandler() PanicHandler {
+	return defaultPanicHandler.Load().(panicHandlerHolder).handler
+}
+
+// runRecovered runs fn and hands a panic to handler, or to the default one when handler is nil.
+func runRecovered(fn func(), handler PanicHandler, callTrace string) (pe *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe = &PanicError{Value: r, Stack: debug.Stack(), CallTrace: callTrace}
+			if handler == nil {
+				handler = currentPanicHandler()
+			}
+			handler.HandlePanic(pe)
+		}
+	}()
+	fn()
+	return nil
+}
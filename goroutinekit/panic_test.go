@@ -0,0 +1,76 @@
+package goroutinekit_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/errorkit"
+	"github.com/ilhammhdd/go-toolkit/goroutinekit"
+)
+
+type panickingJob struct {
+	results chan interface{}
+}
+
+func (pj panickingJob) Work() interface{}         { panic("boom") }
+func (pj panickingJob) Handle(result interface{}) { pj.results <- result }
+
+func TestPanicHandlers(t *testing.T) {
+	errs := make(chan error, 1)
+	var counter goroutinekit.PanicCounter
+	previous := goroutinekit.SetPanicHandler(goroutinekit.PanicHandlers(&counter, goroutinekit.ErrorChannelPanicHandler(errs)))
+	defer goroutinekit.SetPanicHandler(previous)
+
+	goroutinekit.Do(func() { panic("boom") })
+	err := <-errs
+	var detailedErr *errorkit.DetailedError
+	if !errors.As(err, &detailedErr) || detailedErr.Flow || detailedErr.ErrDescConst != errorkit.NonFlowErrPanic {
+		t.Fatalf("expected a non flow panic DetailedError got: %v", err)
+	}
+	if desc := goroutinekit.PanicErrDescGenerator.GenerateDesc(errorkit.NonFlowErrPanic); desc != "panic" {
+		t.Fatalf("expected a generic description without args got: %s", desc)
+	}
+	var panicErr *goroutinekit.PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || !strings.Contains(string(panicErr.Stack), "panic_test.go") {
+		t.Fatalf("expected the panic value and its stack got: %v", err)
+	}
+	if counter.Count() != 1 {
+		t.Fatalf("expected 1 panic counted got: %d", counter.Count())
+	}
+
+	poolCounter := &goroutinekit.PanicCounter{}
+	wp := goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{PanicHandler: poolCounter})
+	defer wp.Shutdown(context.Background())
+	results := make(chan interface{}, 1)
+	wp.Job <- panickingJob{results}
+	result := <-results
+	if err, ok := result.(*errorkit.DetailedError); !ok || !errors.Is(err, goroutinekit.ErrJobPanicked) {
+		t.Fatalf("expected the job result to be the panic got: %v", result)
+	}
+
+	future, err := goroutinekit.Submit(context.Background(), wp, func(ctx context.Context) (int, error) {
+		var m map[string]int
+		m["nil"] = 1
+		return 0, nil
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	_, err = future.Await(context.Background())
+	var runtimeErr interface{ RuntimeError() }
+	if !errors.Is(err, goroutinekit.ErrJobPanicked) || !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected the runtime error panic got: %v", err)
+	}
+	if stats := wp.Stats(); poolCounter.Count() != 2 || stats.Panicked != 2 || counter.Count() != 1 {
+		t.Fatalf("expected 2 panics handled by the pool got: %d %+v", poolCounter.Count(), stats)
+	}
+
+	defer func() {
+		if r, ok := recover().(*goroutinekit.PanicError); !ok || r.Value != "boom" {
+			t.Fatalf("expected RepanicHandler to panic with the PanicError got: %v", r)
+		}
+	}()
+	goroutinekit.RepanicHandler.HandlePanic(&goroutinekit.PanicError{Value: "boom"})
+}
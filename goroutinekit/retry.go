@@ -0,0 +1,238 @@
+package goroutinekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const DefaultMaxAttempts = 3
+
+var ErrRetriesExhausted = errors.New("retries exhausted")
+
+// Backoff tells how long to wait before the next attempt, attempt starts at 1 for the wait after
+// the first failure and previous is the last wait or 0.
+type Backoff interface {
+	Next(attempt int, previous time.Duration) time.Duration
+}
+
+type BackoffFunc func(attempt int, previous time.Duration) time.Duration
+
+func (bf BackoffFunc) Next(attempt int, previous time.Duration) time.Duration {
+	return bf(attempt, previous)
+}
+
+// ConstantBackoff waits Delay between every attempt.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func (cb ConstantBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	return cb.Delay
+}
+
+// ExponentialBackoff waits Initial*Multiplier^(attempt-1) capped at Max. Jitter between 0 and 1
+// spreads each wait randomly by up to that fraction of it.
+type ExponentialBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	// Multiplier is 2 when not above 1
+	Multiplier float64
+	Jitter     float64
+}
+
+func (eb ExponentialBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	multiplier := eb.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	delay := float64(eb.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if eb.Jitter > 0 {
+		delay += delay * eb.Jitter * (2*rand.Float64() - 1)
+	}
+	if eb.Max > 0 && delay > float64(eb.Max) {
+		return eb.Max
+	}
+	// a large attempt overflows the duration, or makes delay +Inf or NaN, so it waits the longest instead
+	if !(delay < math.MaxInt64) {
+		return math.MaxInt64
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+// DecorrelatedJitterBackoff waits a random duration between Base and three times the previous
+// wait, capped at Max.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (djb DecorrelatedJitterBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	if previous < djb.Base {
+		previous = djb.Base
+	}
+	delay := djb.Base
+	if upper := 3 * previous; upper > djb.Base {
+		delay += time.Duration(rand.Int63n(int64(upper - djb.Base)))
+	}
+	if djb.Max > 0 && delay > djb.Max {
+		return djb.Max
+	}
+	return delay
+}
+
+// Attempt describes a finished attempt to the OnAttempt hook, Delay is the wait before the next
+// one and Retrying is false when no next attempt will be made.
+type Attempt struct {
+	Number   int
+	Err      error
+	Elapsed  time.Duration
+	Delay    time.Duration
+	Retrying bool
+}
+
+// RetryPolicy retries failed calls. The zero value makes DefaultMaxAttempts attempts without
+// waiting and retries every error except context errors and ones marked with Permanent.
+type RetryPolicy struct {
+	Backoff Backoff
+	// MaxAttempts counts the first call too, DefaultMaxAttempts when 0 and no limit when negative
+	MaxAttempts int
+	// MaxElapsed stops retrying when the next wait would end after it, no limit when 0
+	MaxElapsed time.Duration
+	// Retryable classifies errors, returning false stops retrying
+	Retryable func(err error) bool
+	OnAttempt func(attempt Attempt)
+}
+
+// RetryError is returned when every allowed attempt failed, it wraps the last error.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (re *RetryError) Error() string {
+	return fmt.Sprintf("%s after %d attempts: %v", ErrRetriesExhausted, re.Attempts, re.Err)
+}
+
+func (re *RetryError) Is(target error) bool { return target == ErrRetriesExhausted }
+
+func (re *RetryError) Unwrap() error { return re.Err }
+
+type permanentError struct {
+	err error
+}
+
+func (pe *permanentError) Error() string { return pe.err.Error() }
+
+func (pe *permanentError) Unwrap() error { return pe.err }
+
+// Permanent marks err as not worth retrying, the retry returns err itself.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+func (rp RetryPolicy) retryable(err error) bool {
+	var permanentErr *permanentError
+	if errors.As(err, &permanentErr) {
+		return false
+	}
+	if rp.Retryable != nil {
+		return rp.Retryable(err)
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Retry calls fn until it succeeds or rp gives up. A non retryable error is returned as is, running
+// out of attempts or time returns *RetryError and ending ctx while waiting returns ctx.Err().
+func Retry(ctx context.Context, rp RetryPolicy, fn func(ctx context.Context) error) error {
+	_, err := RetryValue(ctx, rp, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// RetryValue is Retry for calls with a result.
+func RetryValue[T any](ctx context.Context, rp RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	maxAttempts := rp.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	start := time.Now()
+	var zero T
+	var delay time.Duration
+
+	for number := 1; ; number++ {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		result, err := fn(ctx)
+		if err == nil {
+			rp.observe(Attempt{Number: number, Elapsed: time.Since(start)})
+			return result, nil
+		}
+
+		attempt := Attempt{Number: number, Err: err, Elapsed: time.Since(start)}
+		if !rp.retryable(err) {
+			rp.observe(attempt)
+			var permanentErr *permanentError
+			if errors.As(err, &permanentErr) {
+				err = permanentErr.err
+			}
+			return zero, err
+		}
+		if maxAttempts > 0 && number >= maxAttempts {
+			rp.observe(attempt)
+			return zero, &RetryError{Attempts: number, Err: err}
+		}
+		if rp.Backoff != nil {
+			delay = rp.Backoff.Next(number, delay)
+		}
+		if rp.MaxElapsed > 0 && attempt.Elapsed+delay > rp.MaxElapsed {
+			rp.observe(attempt)
+			return zero, &RetryError{Attempts: number, Err: err}
+		}
+		attempt.Delay, attempt.Retrying = delay, true
+		rp.observe(attempt)
+
+		if err := sleep(ctx, delay); err != nil {
+			return zero, err
+		}
+	}
+}
+
+func (rp RetryPolicy) observe(attempt Attempt) {
+	if rp.OnAttempt != nil {
+		rp.OnAttempt(attempt)
+	}
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SubmitRetry is Submit for fn retried with rp, the job keeps its worker while waiting between
+// attempts.
+func SubmitRetry[T any](ctx context.Context, wp *WorkerPool, rp RetryPolicy, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	return Submit(ctx, wp, func(ctx context.Context) (T, error) {
+		return RetryValue(ctx, rp, fn)
+	})
+}
@@ -0,0 +1,116 @@
+package goroutinekit_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/goroutinekit"
+)
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		backoff  goroutinekit.Backoff
+		min, max time.Duration
+	}{
+		{"constant", goroutinekit.ConstantBackoff{Delay: time.Second}, time.Second, time.Second},
+		{"exponential", goroutinekit.ExponentialBackoff{Initial: time.Second, Max: time.Minute}, 8 * time.Second, 8 * time.Second},
+		{"exponential capped", goroutinekit.ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 3}, 5 * time.Second, 5 * time.Second},
+		{"exponential jitter", goroutinekit.ExponentialBackoff{Initial: time.Second, Jitter: 0.5}, 4 * time.Second, 12 * time.Second},
+		{"decorrelated jitter", goroutinekit.DecorrelatedJitterBackoff{Base: time.Second, Max: time.Minute}, time.Second, 6 * time.Second},
+		{"decorrelated jitter capped", goroutinekit.DecorrelatedJitterBackoff{Base: time.Second, Max: time.Second}, time.Second, time.Second},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 20; i++ {
+			if delay := tc.backoff.Next(4, 2*time.Second); delay < tc.min || delay > tc.max {
+				t.Fatalf("%s expected between %s and %s got: %s", tc.name, tc.min, tc.max, delay)
+			}
+		}
+	}
+
+	largeAttempts := []struct {
+		name     string
+		backoff  goroutinekit.ExponentialBackoff
+		expected time.Duration
+	}{
+		{"uncapped", goroutinekit.ExponentialBackoff{Initial: time.Second}, time.Duration(math.MaxInt64)},
+		{"uncapped jitter", goroutinekit.ExponentialBackoff{Initial: time.Second, Jitter: 0.5}, time.Duration(math.MaxInt64)},
+		{"capped", goroutinekit.ExponentialBackoff{Initial: time.Second, Max: time.Minute}, time.Minute},
+	}
+	for _, tc := range largeAttempts {
+		for _, attempt := range []int{64, 100, 2000} {
+			if delay := tc.backoff.Next(attempt, 0); delay != tc.expected {
+				t.Fatalf("%s attempt %d expected %s got: %s", tc.name, attempt, tc.expected, delay)
+			}
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	errFatal := errors.New("fatal")
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		policy   goroutinekit.RetryPolicy
+		failures []error
+		attempts int
+		expected error
+	}{
+		{"succeeds", goroutinekit.RetryPolicy{}, []error{errFlaky, errFlaky}, 3, nil},
+		{"exhausted", goroutinekit.RetryPolicy{}, []error{errFlaky, errFlaky, errFlaky}, 3, goroutinekit.ErrRetriesExhausted},
+		{"unlimited", goroutinekit.RetryPolicy{MaxAttempts: -1}, []error{errFlaky, errFlaky, errFlaky, errFlaky}, 5, nil},
+		{"permanent", goroutinekit.RetryPolicy{}, []error{goroutinekit.Permanent(errFatal)}, 1, errFatal},
+		{"classifier", goroutinekit.RetryPolicy{Retryable: func(err error) bool { return err == errFlaky }}, []error{errFlaky, errFatal}, 2, errFatal},
+		{"max elapsed", goroutinekit.RetryPolicy{MaxAttempts: -1, Backoff: goroutinekit.ConstantBackoff{Delay: 10 * time.Millisecond}, MaxElapsed: 25 * time.Millisecond}, []error{errFlaky, errFlaky, errFlaky, errFlaky}, 3, goroutinekit.ErrRetriesExhausted},
+	}
+	for _, tc := range testCases {
+		var observed []goroutinekit.Attempt
+		tc.policy.OnAttempt = func(attempt goroutinekit.Attempt) { observed = append(observed, attempt) }
+		calls := 0
+		err := goroutinekit.Retry(ctx, tc.policy, func(ctx context.Context) error {
+			calls++
+			if calls <= len(tc.failures) {
+				return tc.failures[calls-1]
+			}
+			return nil
+		})
+		if !errors.Is(err, tc.expected) || (tc.expected == nil) != (err == nil) || calls != tc.attempts {
+			t.Fatalf("%s expected %v after %d attempts got: %v after %d", tc.name, tc.expected, tc.attempts, err, calls)
+		}
+		if len(observed) != calls || observed[len(observed)-1].Retrying {
+			t.Fatalf("%s expected every attempt observed got: %+v", tc.name, observed)
+		}
+	}
+
+	cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := goroutinekit.Retry(cancelCtx, goroutinekit.RetryPolicy{Backoff: goroutinekit.ConstantBackoff{Delay: time.Second}}, func(ctx context.Context) error {
+		return errFlaky
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected the wait to end with the context got: %v after %s", err, time.Since(start))
+	}
+
+	wp := goroutinekit.NewWorkerPoolWith(ctx, goroutinekit.PoolConfig{MaxWorkers: 1})
+	defer wp.Shutdown(ctx)
+	calls := 0
+	future, err := goroutinekit.SubmitRetry(ctx, wp, goroutinekit.RetryPolicy{Backoff: goroutinekit.ExponentialBackoff{Initial: time.Millisecond}}, func(ctx context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errFlaky
+		}
+		return calls, nil
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result, err := future.Await(ctx); err != nil || result != 3 {
+		t.Fatalf("expected the third attempt to succeed got: %d %v", result, err)
+	}
+}
@@ -1,9 +1,13 @@
 package goroutinekit
 
 import (
-	"os"
-	"os/signal"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Worker interface {
@@ -19,89 +23,426 @@ type Job interface {
 	ResultHandler
 }
 
+var (
+	ErrQueueFull     = errors.New("worker pool queue is full")
+	ErrSubmitTimeout = errors.New("worker pool submit timed out")
+	ErrPoolClosed    = errors.New("worker pool is closed")
+)
+
+const DefaultWorkerIdleTimeout = time.Minute
+
+// PoolConfig bounds a WorkerPool, at most MaxWorkers jobs run at once and at most QueueSize wait.
+type PoolConfig struct {
+	// MinWorkers are always running, at least one
+	MinWorkers int
+	// MaxWorkers defaults to GOMAXPROCS, workers above MinWorkers exit after IdleTimeout without work
+	MaxWorkers  int
+	IdleTimeout time.Duration
+	// QueueSize defaults to MaxWorkers
+	QueueSize int
+	// OnReject is called with ErrQueueFull, ErrSubmitTimeout or ErrPoolClosed for every rejected submission
+	OnReject func(err error)
+	// PanicHandler is told about panicking jobs, the one set with SetPanicHandler when nil
+	PanicHandler PanicHandler
+}
+
+type PoolStats struct {
+	Workers     int
+	IdleWorkers int
+	Queued      int
+	Submitted   uint64
+	Completed   uint64
+	Rejected    uint64
+	Panicked    uint64
+}
+
+// task is a queued job, abandon is called instead of run when the pool is cancelled first and
+// failed when run panics.
+type task struct {
+	run     func()
+	abandon func()
+	failed  func(pe *PanicError)
+}
+
+type poolCounters struct {
+	submitted uint64
+	completed uint64
+	rejected  uint64
+	panicked  uint64
+}
+
+// ShutdownError reports the jobs Shutdown gave up on because its context ended before the pool drained.
+type ShutdownError struct {
+	Queued  int
+	Running int
+	Err     error
+}
+
+func (se *ShutdownError) Error() string {
+	return fmt.Sprintf("worker pool abandoned %d queued and %d running jobs: %s", se.Queued, se.Running, se.Err.Error())
+}
+
+func (se *ShutdownError) Unwrap() error { return se.Err }
+
+// WorkerPool runs jobs on a bounded number of workers. Jobs sent on Job, Work and Worker are
+// submitted with Submit, so a full queue blocks the sender. The channels are never closed, stop
+// sending before Shutdown as nothing receives afterwards.
 type WorkerPool struct {
+	// counters is first so its uint64 are 64-bit aligned for atomic on 32-bit platforms
+	counters poolCounters
+
 	Job    chan Job
 	Work   chan func()
 	Worker chan Worker
+	// Done shuts the pool down like Shutdown without a deadline, PoolWG is done once it drained
 	Done   chan bool
 	PoolWG sync.WaitGroup
+
+	config PoolConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan task
+
+	// closing stops submissions, drain is closed once no submission is in flight anymore
+	closing     chan struct{}
+	drain       chan struct{}
+	closeOnce   sync.Once
+	submitMu    sync.RWMutex
+	workersWG   sync.WaitGroup
+	mu          sync.Mutex
+	workers     int
+	idleWorkers int
+	// pending counts queued jobs no worker has taken yet, it is what scaling compares idleWorkers with
+	pending int
 }
 
+// NewWorkerPool is NewWorkerPoolWith the default PoolConfig and no parent context.
 func NewWorkerPool() *WorkerPool {
-	wp := &WorkerPool{}
+	return NewWorkerPoolWith(context.Background(), PoolConfig{})
+}
 
-	wp.Work = make(chan func())
-	wp.Job = make(chan Job)
-	wp.Worker = make(chan Worker)
-	wp.Done = make(chan bool)
+// NewWorkerPoolWith starts a bounded pool. Cancelling ctx stops it at once, queued jobs are
+// abandoned, Shutdown is the graceful way.
+func NewWorkerPoolWith(ctx context.Context, config PoolConfig) *WorkerPool {
+	if config.MinWorkers < 1 {
+		config.MinWorkers = 1
+	}
+	if config.MaxWorkers <= 0 {
+		config.MaxWorkers = runtime.GOMAXPROCS(0)
+	}
+	if config.MaxWorkers < config.MinWorkers {
+		config.MaxWorkers = config.MinWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = config.MaxWorkers
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultWorkerIdleTimeout
+	}
 
-	wp.PoolWG.Add(3)
+	wp := &WorkerPool{
+		Job:    make(chan Job),
+		Work:   make(chan func()),
+		Worker: make(chan Worker),
+		// buffered for callers that used to send once for each of the old three loops
+		Done:    make(chan bool, 3),
+		config:  config,
+		queue:   make(chan task, config.QueueSize),
+		closing: make(chan struct{}),
+		drain:   make(chan struct{}),
+	}
+	wp.ctx, wp.cancel = context.WithCancel(ctx)
 
-	Do(func() {
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt)
+	wp.mu.Lock()
+	for i := 0; i < config.MinWorkers; i++ {
+		wp.startWorker()
+	}
+	wp.mu.Unlock()
 
-	JobLoop:
+	go func() {
+		<-wp.ctx.Done()
+		wp.close()
+		wp.abandonQueue()
+	}()
+
+	wp.PoolWG.Add(1)
+	Do(func() {
+		defer wp.PoolWG.Done()
 		for {
 			select {
 			case job := <-wp.Job:
-				Do(func() {
-					job.Handle(job.Work())
-				})
+				wp.submitJob(job)
+			case work := <-wp.Work:
+				wp.Submit(work)
+			case worker := <-wp.Worker:
+				wp.Submit(func() { worker.Work() })
 			case <-wp.Done:
-				break JobLoop
-			case <-signals:
-				break JobLoop
+				wp.Shutdown(context.Background())
+				return
+			case <-wp.closing:
+				return
 			}
 		}
+	})
+
+	return wp
+}
+
+// Context is cancelled when the pool is stopped, or when Shutdown gives up on the running jobs.
+// Jobs submitted with a context see it through that context too.
+func (wp *WorkerPool) Context() context.Context {
+	return wp.ctx
+}
+
+// Shutdown stops accepting jobs, runs the queued ones and waits for all of them to return. When
+// ctx ends first the pool context is cancelled, the jobs still queued are never run and a
+// *ShutdownError tells how many jobs were abandoned.
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	wp.close()
+
+	drained := make(chan struct{})
+	go func() {
+		wp.workersWG.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		wp.cancel()
+		return nil
+	case <-ctx.Done():
+		stats := wp.Stats()
+		wp.cancel()
+		return &ShutdownError{Queued: stats.Queued, Running: stats.Workers - stats.IdleWorkers, Err: ctx.Err()}
+	}
+}
 
-		close(wp.Job)
-		wp.PoolWG.Done()
+func (wp *WorkerPool) close() {
+	wp.closeOnce.Do(func() {
+		close(wp.closing)
+		// submissions in flight either queued their job or saw closing, the workers can drain now
+		wp.submitMu.Lock()
+		close(wp.drain)
+		wp.submitMu.Unlock()
 	})
+}
 
-	Do(func() {
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt)
+// Submit queues fn, blocking while the queue is full.
+func (wp *WorkerPool) Submit(fn func()) error {
+	return wp.submit(task{run: fn}, nil, nil, false)
+}
 
-	WorkLoop:
-		for {
+// SubmitContext queues fn, blocking while the queue is full until ctx ends.
+func (wp *WorkerPool) SubmitContext(ctx context.Context, fn func()) error {
+	return wp.submit(task{run: fn}, ctx, nil, false)
+}
+
+// TrySubmit queues fn or fails fast with ErrQueueFull.
+func (wp *WorkerPool) TrySubmit(fn func()) error {
+	return wp.submit(task{run: fn}, nil, nil, true)
+}
+
+// SubmitTimeout queues fn, giving up with ErrSubmitTimeout when the queue stays full for timeout.
+func (wp *WorkerPool) SubmitTimeout(fn func(), timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	return wp.submit(task{run: fn}, nil, timer.C, false)
+}
+
+func (wp *WorkerPool) submit(t task, ctx context.Context, timeout <-chan time.Time, failFast bool) error {
+	wp.submitMu.RLock()
+	defer wp.submitMu.RUnlock()
+
+	select {
+	case <-wp.closing:
+		return wp.reject(ErrPoolClosed)
+	default:
+	}
+
+	select {
+	case wp.queue <- t:
+		wp.submitted()
+		return nil
+	default:
+	}
+
+	if failFast {
+		return wp.reject(ErrQueueFull)
+	}
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	select {
+	case wp.queue <- t:
+		wp.submitted()
+		return nil
+	case <-timeout:
+		return wp.reject(ErrSubmitTimeout)
+	case <-done:
+		return wp.reject(ctx.Err())
+	case <-wp.closing:
+		return wp.reject(ErrPoolClosed)
+	}
+}
+
+// submitted scales up when no worker is idle to take the job just queued.
+func (wp *WorkerPool) submitted() {
+	atomic.AddUint64(&wp.counters.submitted, 1)
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	// an idle worker may already be on its way to another job, only taken jobs stop counting
+	wp.pending++
+	if wp.pending > wp.idleWorkers && wp.workers < wp.config.MaxWorkers {
+		wp.startWorker()
+	}
+}
+
+func (wp *WorkerPool) reject(err error) error {
+	atomic.AddUint64(&wp.counters.rejected, 1)
+	if wp.config.OnReject != nil {
+		wp.config.OnReject(err)
+	}
+	return err
+}
+
+// startWorker must be called with mu held.
+func (wp *WorkerPool) startWorker() {
+	wp.workers++
+	wp.idleWorkers++
+	wp.workersWG.Add(1)
+	go wp.work()
+}
+
+func (wp *WorkerPool) work() {
+	defer wp.workersWG.Done()
+	idle := time.NewTimer(wp.config.IdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case t := <-wp.queue:
+			// a cancelled pool abandons its queue, whichever case select picked
+			if wp.ctx.Err() != nil {
+				t.abandoned()
+				wp.exit()
+				return
+			}
+			wp.run(t)
+		case <-idle.C:
+			wp.mu.Lock()
+			if wp.workers > wp.config.MinWorkers && wp.pending < wp.idleWorkers {
+				wp.workers--
+				wp.idleWorkers--
+				wp.mu.Unlock()
+				return
+			}
+			wp.mu.Unlock()
+		case <-wp.drain:
+			wp.drainQueue()
+			return
+		case <-wp.ctx.Done():
+			wp.exit()
+			return
+		}
+
+		if !idle.Stop() {
 			select {
-			case work := <-wp.Work:
-				Do(func() {
-					work()
-				})
-			case <-wp.Done:
-				break WorkLoop
-			case <-signals:
-				break WorkLoop
+			case <-idle.C:
+			default:
 			}
 		}
+		idle.Reset(wp.config.IdleTimeout)
+	}
+}
 
-		close(wp.Work)
-		wp.PoolWG.Done()
-	})
+// drainQueue runs the queued jobs until the queue is empty or the pool context is cancelled.
+func (wp *WorkerPool) drainQueue() {
+	defer wp.exit()
+	for wp.ctx.Err() == nil {
+		select {
+		case t := <-wp.queue:
+			if wp.ctx.Err() != nil {
+				t.abandoned()
+				return
+			}
+			wp.run(t)
+		default:
+			return
+		}
+	}
+}
 
-	Do(func() {
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt)
+// abandonQueue tells the jobs left in the queue of a cancelled pool that they never run.
+func (wp *WorkerPool) abandonQueue() {
+	for {
+		select {
+		case t := <-wp.queue:
+			t.abandoned()
+		default:
+			return
+		}
+	}
+}
 
-	WorkerLoop:
-		for {
-			select {
-			case worker := <-wp.Worker:
-				Do(func() {
-					worker.Work()
-				})
-			case <-wp.Done:
-				break WorkerLoop
-			case <-signals:
-				break WorkerLoop
-			}
+func (t task) abandoned() {
+	if t.abandon != nil {
+		t.abandon()
+	}
+}
+
+func (wp *WorkerPool) run(t task) {
+	wp.mu.Lock()
+	wp.pending--
+	wp.idleWorkers--
+	wp.mu.Unlock()
+	defer wp.setIdle(1)
+
+	pe := runRecovered(t.run, wp.config.PanicHandler, fmt.Sprintf("%s#WorkerPool", callTraceFilePanic))
+	if pe != nil {
+		atomic.AddUint64(&wp.counters.panicked, 1)
+		if t.failed != nil {
+			t.failed(pe)
 		}
+		return
+	}
+	atomic.AddUint64(&wp.counters.completed, 1)
+}
 
-		close(wp.Worker)
-		wp.PoolWG.Done()
-	})
+// submitJob hands the result handler of a job whose Work panicked the panic as *errorkit.DetailedError.
+func (wp *WorkerPool) submitJob(job Job) error {
+	return wp.submit(task{
+		run:    func() { job.Handle(job.Work()) },
+		failed: func(pe *PanicError) { job.Handle(pe.DetailedError()) },
+	}, nil, nil, false)
+}
 
-	return wp
+func (wp *WorkerPool) exit() {
+	wp.mu.Lock()
+	wp.workers--
+	wp.idleWorkers--
+	wp.mu.Unlock()
+}
+
+func (wp *WorkerPool) setIdle(delta int) {
+	wp.mu.Lock()
+	wp.idleWorkers += delta
+	wp.mu.Unlock()
+}
+
+func (wp *WorkerPool) Stats() PoolStats {
+	wp.mu.Lock()
+	workers, idleWorkers := wp.workers, wp.idleWorkers
+	wp.mu.Unlock()
+
+	return PoolStats{
+		Workers:     workers,
+		IdleWorkers: idleWorkers,
+		Queued:      len(wp.queue),
+		Submitted:   atomic.LoadUint64(&wp.counters.submitted),
+		Completed:   atomic.LoadUint64(&wp.counters.completed),
+		Rejected:    atomic.LoadUint64(&wp.counters.rejected),
+		Panicked:    atomic.LoadUint64(&wp.counters.panicked),
+	}
 }
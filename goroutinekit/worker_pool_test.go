@@ -0,0 +1,156 @@
+package goroutinekit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/goroutinekit"
+)
+
+func TestWorkerPoolBounded(t *testing.T) {
+	var rejected int32
+	wp := goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{
+		MaxWorkers:  2,
+		QueueSize:   2,
+		IdleTimeout: 20 * time.Millisecond,
+		OnReject:    func(err error) { atomic.AddInt32(&rejected, 1) },
+	})
+
+	release := make(chan struct{})
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	blocking := func() {
+		defer wg.Done()
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+	}
+
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		if err := wp.Submit(blocking); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	for wp.Stats().Queued != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	testCases := []struct {
+		name     string
+		submit   func() error
+		expected error
+	}{
+		{"fail fast", func() error { return wp.TrySubmit(func() {}) }, goroutinekit.ErrQueueFull},
+		{"timeout", func() error { return wp.SubmitTimeout(func() {}, 10*time.Millisecond) }, goroutinekit.ErrSubmitTimeout},
+	}
+	for _, tc := range testCases {
+		if err := tc.submit(); !errors.Is(err, tc.expected) {
+			t.Fatalf("%s expected %v got: %v", tc.name, tc.expected, err)
+		}
+	}
+	if atomic.LoadInt32(&rejected) != 2 || wp.Stats().Rejected != 2 {
+		t.Fatalf("expected 2 rejected jobs got: %d %+v", rejected, wp.Stats())
+	}
+
+	close(release)
+	wg.Wait()
+	if maxRunning != 2 {
+		t.Fatalf("expected at most 2 running jobs got: %d", maxRunning)
+	}
+
+	wg.Add(1)
+	wp.Work <- func() { wg.Done() }
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Second)
+	for wp.Stats().Workers != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats := wp.Stats(); stats.Workers != 1 || stats.Completed != 5 || stats.Submitted != 5 {
+		t.Fatalf("expected the pool to scale down to one worker got: %+v", stats)
+	}
+}
+
+func TestWorkerPoolScaling(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		wp := goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{MaxWorkers: 2, QueueSize: 2})
+		releaseA := make(chan struct{})
+		ranB := make(chan struct{})
+		if err := wp.Submit(func() { <-releaseA }); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if err := wp.Submit(func() { close(ranB) }); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		select {
+		case <-ranB:
+		case <-time.After(time.Second):
+			t.Fatalf("expected job B to run while job A blocks got: %+v", wp.Stats())
+		}
+		close(releaseA)
+		if err := wp.Shutdown(context.Background()); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+}
+
+func TestWorkerPoolShutdown(t *testing.T) {
+	wp := goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{MaxWorkers: 2, QueueSize: 8})
+	var ran int32
+	for i := 0; i < 8; i++ {
+		err := wp.Submit(func() {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&ran, 1)
+		})
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	err := wp.Shutdown(context.Background())
+	if err != nil || atomic.LoadInt32(&ran) != 8 {
+		t.Fatalf("expected every queued job to run got: %d %v", ran, err)
+	}
+	if err = wp.Submit(func() {}); !errors.Is(err, goroutinekit.ErrPoolClosed) {
+		t.Fatalf("expected %v got: %v", goroutinekit.ErrPoolClosed, err)
+	}
+
+	wp = goroutinekit.NewWorkerPoolWith(context.Background(), goroutinekit.PoolConfig{MaxWorkers: 1, QueueSize: 2})
+	started := make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		<-wp.Context().Done()
+	})
+	<-started
+	wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = wp.Shutdown(ctx)
+	var shutdownErr *goroutinekit.ShutdownError
+	if !errors.As(err, &shutdownErr) || !errors.Is(err, context.DeadlineExceeded) || shutdownErr.Queued != 1 || shutdownErr.Running != 1 {
+		t.Fatalf("expected one queued and one running job to be abandoned got: %v", err)
+	}
+
+	legacy := goroutinekit.NewWorkerPool()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	legacy.Work <- func() { wg.Done() }
+	wg.Wait()
+	for i := 0; i < 3; i++ {
+		legacy.Done <- true
+	}
+	legacy.PoolWG.Wait()
+	if err = legacy.Submit(func() {}); !errors.Is(err, goroutinekit.ErrPoolClosed) {
+		t.Fatalf("expected %v got: %v", goroutinekit.ErrPoolClosed, err)
+	}
+}
@@ -0,0 +1,160 @@
+package jwtkit
+
+import (
+	"encoding/json"
+)
+
+// RegisteredClaims is meant to be embedded into custom claims structs, encoding/json flattens it
+// so the custom claims end up next to the registered ones.
+type RegisteredClaims struct {
+	Id        string   `json:"jti,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiredAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+}
+
+func (rc *RegisteredClaims) Registered() *RegisteredClaims { return rc }
+
+type Claims interface {
+	Registered() *RegisteredClaims
+}
+
+// TokenIssuer is implemented by every token format, so the format can be picked by configuration.
+type TokenIssuer interface {
+	Issue(subject string, claims map[string]interface{}) (JWTString, error)
+}
+
+type Issuer struct {
+	Signer   Signer
+	Issuer   string
+	Audience Audience
+	// Expiration is always given in milliseconds, whatever TimeUnit the token uses
+	Expiration JWTExpiration
+	Clock      Clock
+	TimeUnit   TimeUnit
+}
+
+// fill sets every registered claim the caller left empty.
+func (i *Issuer) fill(rc *RegisteredClaims) error {
+	if rc.Id == "" {
+		jti, err := newJTI()
+		if err != nil {
+			return err
+		}
+		rc.Id = jti
+	}
+	if rc.Issuer == "" {
+		rc.Issuer = i.Issuer
+	}
+	if len(rc.Audience) == 0 {
+		rc.Audience = i.Audience
+	}
+	now := i.TimeUnit.now(i.Clock)
+	if rc.IssuedAt == 0 {
+		rc.IssuedAt = now
+	}
+	if rc.ExpiredAt == 0 && i.Expiration != 0 {
+		rc.ExpiredAt = now + i.Expiration.in(i.TimeUnit)
+	}
+
+	return nil
+}
+
+// Issue signs a standard token for subject with map based custom claims.
+func (i *Issuer) Issue(subject string, claims map[string]interface{}) (JWTString, error) {
+	return i.IssueRegistered(&RegisteredClaims{Subject: subject}, claims)
+}
+
+// IssueRegistered is Issue for callers that set registered claims themselves, the empty ones are filled in.
+func (i *Issuer) IssueRegistered(rc *RegisteredClaims, claims map[string]interface{}) (JWTString, error) {
+	err := i.fill(rc)
+	if err != nil {
+		return "", err
+	}
+
+	payload := &Payload{
+		Id:        rc.Id,
+		Audiences: rc.Audience,
+		Issuer:    rc.Issuer,
+		Subject:   rc.Subject,
+		IssuedAt:  rc.IssuedAt,
+		ExpiredAt: rc.ExpiredAt,
+		NotBefore: rc.NotBefore,
+		Claims:    claims,
+	}
+	jsonPayload, err := payload.marshalFlat()
+	if err != nil {
+		return "", err
+	}
+
+	return signCompact(i.Signer, &Header{Type: "JWT"}, jsonPayload)
+}
+
+// Sign fills the empty registered claims of claims in place and signs it as a standard token.
+func Sign[T Claims](i *Issuer, claims T) (JWTString, error) {
+	return signClaims(i, claims, "JWT")
+}
+
+func signClaims[T Claims](i *Issuer, claims T, typ string) (JWTString, error) {
+	err := i.fill(claims.Registered())
+	if err != nil {
+		return "", err
+	}
+
+	jsonPayload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return signCompact(i.Signer, &Header{Type: typ}, jsonPayload)
+}
+
+// ParseAndVerify verifies the signature, validates the registered claims and only then decodes
+// the payload into T, a nil validator still checks exp and nbf in the NumericDate seconds Issuer uses.
+func ParseAndVerify[T any](j JWTString, resolver VerifierResolver, validator *Validator) (*T, error) {
+	_, _, decodedPayload, err := verifyAndValidate(j, resolver, validator)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims T
+	err = json.Unmarshal(decodedPayload, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func verifyAndValidate(j JWTString, resolver VerifierResolver, validator *Validator) (*Header, *Payload, []byte, error) {
+	pt, err := parseToken(j)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, err = verifyStandardJWTString(resolver, pt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var payload Payload
+	err = payload.unmarshalFlat(pt.payload)
+	if err != nil {
+		return nil, nil, nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+
+	if validator == nil {
+		validator = &Validator{}
+	}
+	if pt.header.Type == TypeRefreshJWT && !validator.AllowRefreshTokens {
+		return nil, nil, nil, ErrRefreshTokenRefused
+	}
+	err = validator.Validate(&payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pt.header, &payload, pt.payload, nil
+}
@@ -0,0 +1,90 @@
+package jwtkit
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is where issuers, validators and stores take the current time from, tests can freeze
+// or advance it instead of sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+type ClockFunc func() time.Time
+
+func (cf ClockFunc) Now() time.Time { return cf() }
+
+var SystemClock Clock = ClockFunc(time.Now)
+
+// FrozenClock only moves when Set or Advance is called.
+type FrozenClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFrozenClock(now time.Time) *FrozenClock {
+	return &FrozenClock{now: now}
+}
+
+func (fc *FrozenClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *FrozenClock) Set(now time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = now
+}
+
+func (fc *FrozenClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+}
+
+// TimeUnit is the unit of iat, exp and nbf. The zero value is the RFC 7519 NumericDate seconds,
+// Milliseconds is the legacy unit, opt into it only for consumers of legacy tokens.
+type TimeUnit int
+
+const (
+	Seconds TimeUnit = iota
+	Milliseconds
+)
+
+func (tu TimeUnit) Timestamp(t time.Time) int64 {
+	if tu == Seconds {
+		return t.Unix()
+	}
+	return t.UnixNano() / 1000000
+}
+
+// Duration converts d into the unit, rounding down.
+func (tu TimeUnit) Duration(d time.Duration) int64 {
+	if tu == Seconds {
+		return int64(d / time.Second)
+	}
+	return d.Milliseconds()
+}
+
+func (tu TimeUnit) Time(timestamp int64) time.Time {
+	if tu == Seconds {
+		return time.Unix(timestamp, 0)
+	}
+	return time.Unix(0, timestamp*int64(time.Millisecond))
+}
+
+// in converts the millisecond expiration into the unit.
+func (je JWTExpiration) in(tu TimeUnit) int64 {
+	return tu.Duration(time.Duration(je) * time.Millisecond)
+}
+
+// now is the current timestamp in the unit, a nil clock is the SystemClock.
+func (tu TimeUnit) now(clock Clock) int64 {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return tu.Timestamp(clock.Now())
+}
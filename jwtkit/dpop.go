@@ -0,0 +1,339 @@
+package jwtkit
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TypeDPoP          = "dpop+jwt"
+	ConfirmationClaim = "cnf"
+	// DefaultDPoPMaxAge is how long a proof is accepted after its iat when DPoPVerifier.MaxAge is zero
+	DefaultDPoPMaxAge = time.Minute
+)
+
+// DPoPAlgorithms are the asymmetric algorithms a proof may be signed with.
+var DPoPAlgorithms = []string{
+	AlgorithmES256, AlgorithmES384, AlgorithmES512,
+	AlgorithmRS256, AlgorithmRS384, AlgorithmRS512, AlgorithmPS256, AlgorithmEdDSA,
+}
+
+var (
+	ErrInvalidDPoPProof    = errors.New("invalid DPoP proof")
+	ErrDPoPReplayed        = errors.New("DPoP proof is replayed")
+	ErrDPoPBindingMismatch = errors.New("token is not bound to the DPoP key")
+)
+
+// Thumbprint is the RFC 7638 SHA-256 thumbprint of the key, base64url encoded as cnf.jkt expects.
+func (jwk *JWK) Thumbprint() (string, error) {
+	// only the required members in lexicographic order, encoding/json sorts map keys
+	var members map[string]string
+	switch jwk.KeyType {
+	case KeyTypeEC:
+		members = map[string]string{"crv": jwk.Curve, "kty": jwk.KeyType, "x": jwk.X, "y": jwk.Y}
+	case KeyTypeRSA:
+		members = map[string]string{"e": jwk.E, "kty": jwk.KeyType, "n": jwk.N}
+	case KeyTypeOKP:
+		members = map[string]string{"crv": jwk.Curve, "kty": jwk.KeyType, "x": jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported JWK key type %q", jwk.KeyType)
+	}
+	for name, member := range members {
+		if member == "" {
+			return "", fmt.Errorf("JWK is missing %s", name)
+		}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// WithConfirmation returns a copy of claims binding the token to the DPoP key with thumbprint jkt.
+func WithConfirmation(claims map[string]interface{}, jkt string) map[string]interface{} {
+	bound := make(map[string]interface{}, len(claims)+1)
+	for key, val := range claims {
+		bound[key] = val
+	}
+	bound[ConfirmationClaim] = map[string]interface{}{"jkt": jkt}
+	return bound
+}
+
+// ConfirmationThumbprint is the cnf.jkt claim, empty when the token is not bound to a DPoP key.
+func (p *Payload) ConfirmationThumbprint() string {
+	cnf, ok := p.Claims[ConfirmationClaim].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	jkt, _ := cnf["jkt"].(string)
+	return jkt
+}
+
+// AccessTokenHash is the ath claim of a DPoP proof for accessToken.
+func AccessTokenHash(accessToken JWTString) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// DPoPProver creates the proofs a client sends along its requests, JWK is the public part of Signer.
+type DPoPProver struct {
+	Signer Signer
+	JWK    *JWK
+	Clock  Clock
+}
+
+func NewDPoPProver(key crypto.Signer) (*DPoPProver, error) {
+	signer, err := SignerFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := NewJWK("", signer.Algorithm(), key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &DPoPProver{Signer: signer, JWK: jwk}, nil
+}
+
+// Proof signs a proof for one request, accessToken is empty when no token is presented yet.
+func (dp *DPoPProver) Proof(method string, htu string, accessToken JWTString) (JWTString, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+	claims := &dpopClaims{Id: jti, Method: method, URL: htu, IssuedAt: Seconds.now(dp.Clock)}
+	if accessToken != "" {
+		claims.AccessTokenHash = AccessTokenHash(accessToken)
+	}
+	jsonPayload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return signCompact(dp.Signer, &Header{Type: TypeDPoP, JWK: dp.JWK}, jsonPayload)
+}
+
+type dpopClaims struct {
+	Id              string `json:"jti"`
+	Method          string `json:"htm"`
+	URL             string `json:"htu"`
+	IssuedAt        int64  `json:"iat"`
+	AccessTokenHash string `json:"ath,omitempty"`
+}
+
+// DPoPProof is a verified proof, Thumbprint is compared with the cnf.jkt of the access token.
+type DPoPProof struct {
+	Header          *Header
+	Id              string
+	Method          string
+	URL             string
+	IssuedAt        int64
+	AccessTokenHash string
+	Thumbprint      string
+}
+
+// Confirms checks that the access token with payload p is bound to the key of the proof.
+func (dp *DPoPProof) Confirms(p *Payload) error {
+	jkt := p.ConfirmationThumbprint()
+	if jkt == "" || jkt != dp.Thumbprint {
+		return ErrDPoPBindingMismatch
+	}
+	return nil
+}
+
+// ReplayCache remembers proof jti for ttl, Seen reports whether key was stored before and stores it
+// otherwise, both in one atomic step.
+type ReplayCache interface {
+	Seen(key string, ttl time.Duration) (bool, error)
+}
+
+type MemoryReplayCache struct {
+	Clock Clock
+	mu    sync.Mutex
+	seen  map[string]int64
+}
+
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{seen: make(map[string]int64)}
+}
+
+func (mrc *MemoryReplayCache) Seen(key string, ttl time.Duration) (bool, error) {
+	mrc.mu.Lock()
+	defer mrc.mu.Unlock()
+
+	now := Milliseconds.now(mrc.Clock)
+	for seenKey, expiredAt := range mrc.seen {
+		if expiredAt <= now {
+			delete(mrc.seen, seenKey)
+		}
+	}
+	if _, ok := mrc.seen[key]; ok {
+		return true, nil
+	}
+	mrc.seen[key] = now + ttl.Milliseconds()
+
+	return false, nil
+}
+
+func (mrc *MemoryReplayCache) Len() int {
+	mrc.mu.Lock()
+	defer mrc.mu.Unlock()
+	return len(mrc.seen)
+}
+
+// DPoPVerifier verifies RFC 9449 proofs. The proof iat is always in seconds as clients send it,
+// whatever TimeUnit the access tokens use. A nil Replay disables replay detection.
+type DPoPVerifier struct {
+	Clock  Clock
+	MaxAge time.Duration
+	Leeway time.Duration
+	Replay ReplayCache
+}
+
+// Verify verifies the proof for a request to method and requestURL, accessToken is empty when the proof is
+// not sent along an access token, otherwise ath must match it.
+func (dv *DPoPVerifier) Verify(proof JWTString, method string, requestURL string, accessToken JWTString) (*DPoPProof, error) {
+	pt, err := parseToken(proof)
+	if err != nil {
+		return nil, err
+	}
+	header := pt.header
+	if header.Type != TypeDPoP {
+		return nil, fmt.Errorf("%w: typ %q", ErrInvalidDPoPProof, header.Type)
+	}
+	if !isDPoPAlgorithm(header.Algorithm) {
+		return nil, fmt.Errorf("%w: alg %q", ErrInvalidDPoPProof, header.Algorithm)
+	}
+	if header.JWK == nil {
+		return nil, fmt.Errorf("%w: missing jwk", ErrInvalidDPoPProof)
+	}
+	err = checkPublicJWK(pt.segments[0])
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := header.JWK.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+	_, err = verifyStandardJWTString(KeyVerifierResolver(publicKey), pt)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims dpopClaims
+	err = json.Unmarshal(pt.payload, &claims)
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+	if claims.Id == "" || claims.Method == "" || claims.URL == "" || claims.IssuedAt == 0 {
+		return nil, fmt.Errorf("%w: jti, htm, htu and iat are required", ErrInvalidDPoPProof)
+	}
+	if claims.Method != method {
+		return nil, fmt.Errorf("%w: htm %q", ErrInvalidDPoPProof, claims.Method)
+	}
+	if !sameHTU(claims.URL, requestURL) {
+		return nil, fmt.Errorf("%w: htu %q", ErrInvalidDPoPProof, claims.URL)
+	}
+	if accessToken != "" && claims.AccessTokenHash != AccessTokenHash(accessToken) {
+		return nil, fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+	}
+
+	maxAge := dv.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultDPoPMaxAge
+	}
+	now := Seconds.now(dv.Clock)
+	leeway := Seconds.Duration(dv.Leeway)
+	if claims.IssuedAt > now+leeway {
+		return nil, fmt.Errorf("%w: issued at %d", ErrIssuedInFuture, claims.IssuedAt)
+	}
+	if now > claims.IssuedAt+Seconds.Duration(maxAge)+leeway {
+		return nil, fmt.Errorf("%w: issued at %d", ErrTokenTooOld, claims.IssuedAt)
+	}
+
+	thumbprint, err := header.JWK.Thumbprint()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+	if dv.Replay != nil {
+		// a proof is refused after MaxAge anyway, so it only has to be remembered that long
+		replayed, err := dv.Replay.Seen(thumbprint+":"+claims.Id, maxAge+2*dv.Leeway)
+		if err != nil {
+			return nil, err
+		}
+		if replayed {
+			return nil, fmt.Errorf("%w: jti %s", ErrDPoPReplayed, claims.Id)
+		}
+	}
+
+	return &DPoPProof{
+		Header:          header,
+		Id:              claims.Id,
+		Method:          claims.Method,
+		URL:             claims.URL,
+		IssuedAt:        claims.IssuedAt,
+		AccessTokenHash: claims.AccessTokenHash,
+		Thumbprint:      thumbprint,
+	}, nil
+}
+
+func isDPoPAlgorithm(alg string) bool {
+	for _, dpopAlg := range DPoPAlgorithms {
+		if alg == dpopAlg {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPublicJWK refuses a jwk header carrying private key members, JWK itself drops them silently.
+func checkPublicJWK(headerSegment string) error {
+	headerJSON, err := rawEncoding.DecodeString(headerSegment)
+	if err != nil {
+		return &ParseError{SegmentHeader, ErrBadEncoding, err.Error()}
+	}
+	var header struct {
+		JWK map[string]json.RawMessage `json:"jwk"`
+	}
+	err = json.Unmarshal(headerJSON, &header)
+	if err != nil {
+		return &ParseError{SegmentHeader, ErrMalformed, err.Error()}
+	}
+	for _, member := range []string{"d", "p", "q", "dp", "dq", "qi", "k"} {
+		if _, ok := header.JWK[member]; ok {
+			return fmt.Errorf("%w: jwk contains the private key", ErrInvalidDPoPProof)
+		}
+	}
+	return nil
+}
+
+// sameHTU compares htu without query and fragment, scheme and host case insensitively.
+func sameHTU(htu string, requestURL string) bool {
+	proofURL, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	expectedURL, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	return normalizeHTU(proofURL) == normalizeHTU(expectedURL)
+}
+
+func normalizeHTU(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + path
+}
@@ -0,0 +1,127 @@
+package jwtkit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func signJSON(t *testing.T, signer jwtkit.Signer, header map[string]interface{}, payload map[string]interface{}) jwtkit.JWTString {
+	header["alg"] = signer.Algorithm()
+	jsonHeader, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(jsonHeader) + "." + base64.RawURLEncoding.EncodeToString(jsonPayload)
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return jwtkit.JWTString(signingInput + "." + base64.RawURLEncoding.EncodeToString(signature))
+}
+
+func TestDPoP(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	clock := jwtkit.NewFrozenClock(time.Now())
+	prover, err := jwtkit.NewDPoPProver(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	prover.Clock = clock
+	jkt, err := prover.JWK.Thumbprint()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	bare := &jwtkit.JWK{KeyType: prover.JWK.KeyType, Curve: prover.JWK.Curve, X: prover.JWK.X, Y: prover.JWK.Y}
+	if bareJKT, _ := bare.Thumbprint(); bareJKT != jkt {
+		t.Fatalf("expected optional members to be left out of the thumbprint got: %s %s", bareJKT, jkt)
+	}
+
+	signer, err := jwtkit.SignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	accessToken, err := (&jwtkit.Issuer{Signer: signer, Expiration: 60000}).Issue("subject", jwtkit.WithConfirmation(nil, jkt))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	const htu = "https://api.example.com/resource"
+	proof := func(method string, url string, token jwtkit.JWTString) jwtkit.JWTString {
+		p, err := prover.Proof(method, url, token)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		return p
+	}
+	hmacSigner, err := jwtkit.NewSigner(jwtkit.AlgorithmHS256, []byte("a secret that is long enough for HS256"))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	validClaims := map[string]interface{}{"jti": "1", "htm": "GET", "htu": htu, "iat": clock.Now().Unix()}
+	privateJWK := map[string]interface{}{"kty": "EC", "crv": "P-256", "x": prover.JWK.X, "y": prover.JWK.Y, "d": "secret"}
+
+	dv := &jwtkit.DPoPVerifier{Clock: clock, Replay: jwtkit.NewMemoryReplayCache()}
+	replayed := proof("GET", htu, accessToken)
+
+	testCases := []struct {
+		name     string
+		proof    jwtkit.JWTString
+		method   string
+		url      string
+		expected error
+	}{
+		{"valid", replayed, "GET", htu, nil},
+		{"replayed", replayed, "GET", htu, jwtkit.ErrDPoPReplayed},
+		{"query and fragment ignored", proof("GET", htu+"?a=b#c", accessToken), "GET", "HTTPS://API.example.com/resource", nil},
+		{"other method", proof("POST", htu, accessToken), "GET", htu, jwtkit.ErrInvalidDPoPProof},
+		{"other url", proof("GET", "https://api.example.com/other", accessToken), "GET", htu, jwtkit.ErrInvalidDPoPProof},
+		{"other access token", proof("GET", htu, accessToken+"x"), "GET", htu, jwtkit.ErrInvalidDPoPProof},
+		{"access token as proof", accessToken, "GET", htu, jwtkit.ErrInvalidDPoPProof},
+		{"symmetric", signJSON(t, hmacSigner, map[string]interface{}{"typ": "dpop+jwt", "jwk": prover.JWK}, validClaims), "GET", htu, jwtkit.ErrInvalidDPoPProof},
+		{"private jwk", signJSON(t, signer, map[string]interface{}{"typ": "dpop+jwt", "jwk": privateJWK}, validClaims), "GET", htu, jwtkit.ErrInvalidDPoPProof},
+		{"missing jti", signJSON(t, signer, map[string]interface{}{"typ": "dpop+jwt", "jwk": prover.JWK}, map[string]interface{}{"htm": "GET", "htu": htu, "iat": clock.Now().Unix()}), "GET", htu, jwtkit.ErrInvalidDPoPProof},
+	}
+
+	for _, tc := range testCases {
+		_, err := dv.Verify(tc.proof, tc.method, tc.url, accessToken)
+		if !errors.Is(err, tc.expected) {
+			t.Fatalf("%s expected %v got: %v", tc.name, tc.expected, err)
+		}
+	}
+
+	old := proof("GET", htu, accessToken)
+	clock.Advance(2 * jwtkit.DefaultDPoPMaxAge)
+	_, err = dv.Verify(old, "GET", htu, accessToken)
+	if !errors.Is(err, jwtkit.ErrTokenTooOld) {
+		t.Fatalf("expected %v got: %v", jwtkit.ErrTokenTooOld, err)
+	}
+
+	verified, err := dv.Verify(proof("GET", htu, accessToken), "GET", htu, accessToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	jwt, err := jwtkit.GetJWT(accessToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err = verified.Confirms(jwt.Payload); err != nil {
+		t.Fatalf("expected the access token to be bound got: %v", err)
+	}
+	if err = verified.Confirms(&jwtkit.Payload{}); !errors.Is(err, jwtkit.ErrDPoPBindingMismatch) {
+		t.Fatalf("expected %v got: %v", jwtkit.ErrDPoPBindingMismatch, err)
+	}
+}
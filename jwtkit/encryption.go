@@ -1,20 +1,21 @@
 package jwtkit
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
-	"io/ioutil"
 	"os"
+	"sync"
 )
 
 type Encryptor interface {
 	generatingPublicPrivateToPEM() error
-	gettingPublicFromPEM(string) (*ecdsa.PublicKey, error)
-	gettingPrivateFromPEM(string) (*ecdsa.PrivateKey, error)
+	gettingPublicFromPEM() (*ecdsa.PublicKey, error)
+	gettingPrivateFromPEM() (*ecdsa.PrivateKey, error)
 }
 
 func GeneratePublicPrivateToPEM(e Encryptor) error {
@@ -26,12 +27,7 @@ func GeneratePublicPrivateToPEM(e Encryptor) error {
 }
 
 func GetPublicFromPEM(e Encryptor) (*ecdsa.PublicKey, error) {
-	enc, ok := e.(*ECDSA)
-	if !ok {
-		return nil, errors.New("type assertion failed")
-	}
-
-	publicKey, err := e.gettingPublicFromPEM(enc.PublicKeyPath)
+	publicKey, err := e.gettingPublicFromPEM()
 	if err != nil {
 		return nil, err
 	}
@@ -40,12 +36,7 @@ func GetPublicFromPEM(e Encryptor) (*ecdsa.PublicKey, error) {
 }
 
 func GetPrivateFromPEM(e Encryptor) (*ecdsa.PrivateKey, error) {
-	enc, ok := e.(*ECDSA)
-	if !ok {
-		return nil, errors.New("type assertion failed")
-	}
-
-	privateKey, err := e.gettingPrivateFromPEM(enc.PrivateKeyPath)
+	privateKey, err := e.gettingPrivateFromPEM()
 	if err != nil {
 		return nil, err
 	}
@@ -114,24 +105,20 @@ func (enc *ECDSA) generatingPublicPrivateToPEM() error {
 	return nil
 }
 
-func (enc *ECDSA) gettingPublicFromPEM(string) (*ecdsa.PublicKey, error) {
-	publicKeyData, err := ioutil.ReadFile(enc.PublicKeyPath)
-	if err != nil {
-		return nil, err
-	}
+// ecdsaKeyFiles caches the keys ECDSA reads by path, it lives outside ECDSA so the config stays copyable.
+var ecdsaKeyFiles sync.Map
 
-	publicPemBlock, _ := pem.Decode(publicKeyData)
-	if publicPemBlock == nil || publicPemBlock.Type != "E256 PUBLIC KEY" {
-		return nil, errors.New("failed to decode PEM block containing public key")
-	}
+func loadECDSAKeyFile(path string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	ckf, _ := ecdsaKeyFiles.LoadOrStore(path, &cachedKeyFile{})
+	return ckf.(*cachedKeyFile).load(path, parse)
+}
 
-	parsedPublicKey, err := x509.ParsePKIXPublicKey(publicPemBlock.Bytes)
+func (enc *ECDSA) gettingPublicFromPEM() (*ecdsa.PublicKey, error) {
+	parsedPublicKey, err := loadECDSAKeyFile(enc.PublicKeyPath, func(data []byte) (interface{}, error) {
+		return ParsePublicKeyPEM(data)
+	})
 	if err != nil {
-		if cert, err := x509.ParseCertificate(publicPemBlock.Bytes); err == nil {
-			parsedPublicKey = cert.PublicKey
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	publicKey, ok := parsedPublicKey.(*ecdsa.PublicKey)
@@ -142,21 +129,42 @@ func (enc *ECDSA) gettingPublicFromPEM(string) (*ecdsa.PublicKey, error) {
 	return publicKey, nil
 }
 
-func (enc *ECDSA) gettingPrivateFromPEM(string) (*ecdsa.PrivateKey, error) {
-	privateKeyData, err := ioutil.ReadFile(enc.PrivateKeyPath)
+func (enc *ECDSA) gettingPrivateFromPEM() (*ecdsa.PrivateKey, error) {
+	parsedPrivateKey, err := loadECDSAKeyFile(enc.PrivateKeyPath, func(data []byte) (interface{}, error) {
+		return ParsePrivateKeyPEM(data)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	privatePemBlock, _ := pem.Decode(privateKeyData)
-	if privatePemBlock == nil || privatePemBlock.Type != "E256 PRIVATE KEY" {
-		return nil, errors.New("failed to decode PEM block containing private key")
+	privateKey, ok := parsedPrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not ECDSA private key")
 	}
 
-	privateKey, err := x509.ParseECPrivateKey(privatePemBlock.Bytes)
+	return privateKey, nil
+}
+
+func (enc *ECDSA) PrivateKey() (crypto.PrivateKey, error) {
+	return enc.gettingPrivateFromPEM()
+}
+
+func (enc *ECDSA) PublicKey() (crypto.PublicKey, error) {
+	return enc.gettingPublicFromPEM()
+}
+
+func (enc *ECDSA) Signer() (Signer, error) {
+	privateKey, err := enc.gettingPrivateFromPEM()
 	if err != nil {
 		return nil, err
 	}
+	return SignerFromKey(privateKey)
+}
 
-	return privateKey, nil
+func (enc *ECDSA) ResolveVerifier(header *Header) (Verifier, error) {
+	publicKey, err := enc.gettingPublicFromPEM()
+	if err != nil {
+		return nil, err
+	}
+	return NewVerifier(header.Algorithm, publicKey)
 }
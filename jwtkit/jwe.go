@@ -0,0 +1,427 @@
+package jwtkit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	KeyAlgorithmECDHES       = "ECDH-ES"
+	KeyAlgorithmECDHESA256KW = "ECDH-ES+A256KW"
+	EncryptionA256GCM        = "A256GCM"
+	// ContentTypeJWT marks a JWE whose plaintext is a signed token
+	ContentTypeJWT = "JWT"
+)
+
+const (
+	SegmentEncryptedKey = "encrypted_key"
+	SegmentIV           = "iv"
+	SegmentCiphertext   = "ciphertext"
+	SegmentTag          = "tag"
+)
+
+const (
+	a256KeySize = 32
+	gcmIVSize   = 12
+	gcmTagSize  = 16
+)
+
+// ErrDecryption is deliberately vague, telling why decryption failed would help an attacker.
+var ErrDecryption = errors.New("token decryption failed")
+
+type JWEHeader struct {
+	Algorithm          string `json:"alg"`
+	Encryption         string `json:"enc"`
+	KeyID              string `json:"kid,omitempty"`
+	Type               string `json:"typ,omitempty"`
+	ContentType        string `json:"cty,omitempty"`
+	EphemeralPublicKey *JWK   `json:"epk"`
+	PartyUInfo         string `json:"apu,omitempty"`
+	PartyVInfo         string `json:"apv,omitempty"`
+}
+
+// Encrypter encrypts RFC 7516 compact JWE for the holder of the private key of Recipient.
+type Encrypter struct {
+	// Algorithm is KeyAlgorithmECDHES or KeyAlgorithmECDHESA256KW, content is always A256GCM
+	Algorithm string
+	Recipient *ecdsa.PublicKey
+	KeyID     string
+}
+
+// NewEncrypter takes the recipient key from a KeyProvider, e.g. an *ECDSA holding only a public key path.
+func NewEncrypter(alg string, kp KeyProvider) (*Encrypter, error) {
+	publicKey, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	recipient, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s requires *ecdsa.PublicKey got %T", ErrAlgorithmKeyMismatch, alg, publicKey)
+	}
+	if alg != KeyAlgorithmECDHES && alg != KeyAlgorithmECDHESA256KW {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+	}
+	return &Encrypter{Algorithm: alg, Recipient: recipient}, nil
+}
+
+func (e *Encrypter) Encrypt(plaintext []byte, contentType string) (JWTString, error) {
+	if e.Algorithm != KeyAlgorithmECDHES && e.Algorithm != KeyAlgorithmECDHESA256KW {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedAlg, e.Algorithm)
+	}
+
+	ephemeral, err := ecdsa.GenerateKey(e.Recipient.Curve, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	epk, err := NewJWK("", "", &ephemeral.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	epk.Use, epk.Algorithm = "", ""
+
+	header := &JWEHeader{
+		Algorithm:          e.Algorithm,
+		Encryption:         EncryptionA256GCM,
+		KeyID:              e.KeyID,
+		ContentType:        contentType,
+		EphemeralPublicKey: epk,
+	}
+	agreedKey, err := deriveECDHESKey(header, ephemeral, e.Recipient)
+	if err != nil {
+		return "", err
+	}
+
+	contentKey := agreedKey
+	var encryptedKey []byte
+	if e.Algorithm == KeyAlgorithmECDHESA256KW {
+		contentKey = make([]byte, a256KeySize)
+		_, err = rand.Read(contentKey)
+		if err != nil {
+			return "", err
+		}
+		encryptedKey, err = aesKeyWrap(agreedKey, contentKey)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	jsonHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(jsonHeader)
+
+	gcm, err := newGCM(contentKey)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcmIVSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(encodedHeader))
+	ciphertext, tag := sealed[:len(sealed)-gcmTagSize], sealed[len(sealed)-gcmTagSize:]
+
+	return JWTString(strings.Join([]string{
+		encodedHeader,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, ".")), nil
+}
+
+// EncryptSigned signs a token with issuer and encrypts it, the result is a nested JWT.
+func (e *Encrypter) EncryptSigned(issuer *Issuer, subject string, claims map[string]interface{}) (JWTString, error) {
+	signed, err := issuer.Issue(subject, claims)
+	if err != nil {
+		return "", err
+	}
+	return e.Encrypt([]byte(signed), ContentTypeJWT)
+}
+
+// Decrypter decrypts tokens encrypted for the private key of Keys.
+type Decrypter struct {
+	Keys KeyProvider
+}
+
+func (d *Decrypter) Decrypt(j JWTString) (*JWEHeader, []byte, error) {
+	pj, err := parseJWE(j)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := d.Keys.PrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	recipient, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s requires *ecdsa.PrivateKey got %T", ErrAlgorithmKeyMismatch, pj.header.Algorithm, privateKey)
+	}
+
+	epk, err := pj.header.EphemeralPublicKey.PublicKey()
+	if err != nil {
+		return nil, nil, &ParseError{SegmentHeader, ErrMalformed, "epk: " + err.Error()}
+	}
+	ephemeral, ok := epk.(*ecdsa.PublicKey)
+	if !ok || ephemeral.Curve != recipient.Curve {
+		return nil, nil, &ParseError{SegmentHeader, ErrMalformed, "epk is not on the recipient curve"}
+	}
+
+	agreedKey, err := deriveECDHESKey(pj.header, recipient, ephemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	contentKey := agreedKey
+	if pj.header.Algorithm == KeyAlgorithmECDHESA256KW {
+		contentKey, err = aesKeyUnwrap(agreedKey, pj.encryptedKey)
+		if err != nil {
+			return nil, nil, ErrDecryption
+		}
+	}
+
+	gcm, err := newGCM(contentKey)
+	if err != nil {
+		return nil, nil, ErrDecryption
+	}
+	plaintext, err := gcm.Open(nil, pj.iv, append(pj.ciphertext, pj.tag...), []byte(pj.segments[0]))
+	if err != nil {
+		return nil, nil, ErrDecryption
+	}
+
+	return pj.header, plaintext, nil
+}
+
+// DecryptAndVerify decrypts a nested JWT and verifies and validates the signed token inside it.
+func (d *Decrypter) DecryptAndVerify(j JWTString, verifier TokenVerifier) (*JWT, error) {
+	header, plaintext, err := d.Decrypt(j)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(header.ContentType, ContentTypeJWT) {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, fmt.Sprintf("cty %q is not a nested JWT", header.ContentType)}
+	}
+	return verifier.VerifyToken(JWTString(plaintext))
+}
+
+// NestedJWTVerifier lets anything taking a TokenVerifier, such as the restkit bearer middleware,
+// accept sign-then-encrypt tokens.
+type NestedJWTVerifier struct {
+	Decrypter *Decrypter
+	Verifier  TokenVerifier
+}
+
+func (njv *NestedJWTVerifier) VerifyToken(j JWTString) (*JWT, error) {
+	return njv.Decrypter.DecryptAndVerify(j, njv.Verifier)
+}
+
+type parsedJWE struct {
+	segments     []string
+	header       *JWEHeader
+	encryptedKey []byte
+	iv           []byte
+	ciphertext   []byte
+	tag          []byte
+}
+
+func parseJWE(j JWTString) (*parsedJWE, error) {
+	if len(j) > MaxTokenSize {
+		return nil, &ParseError{SegmentToken, ErrMalformed, fmt.Sprintf("longer than %d bytes", MaxTokenSize)}
+	}
+	segments := strings.Split(string(j), ".")
+	if len(segments) != 5 {
+		return nil, &ParseError{SegmentToken, ErrMalformed, fmt.Sprintf("%d segments, expected 5", len(segments))}
+	}
+
+	headerJSON, err := rawEncoding.DecodeString(segments[0])
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrBadEncoding, err.Error()}
+	}
+	err = checkJSONObject(headerJSON)
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, err.Error()}
+	}
+	var header JWEHeader
+	err = json.Unmarshal(headerJSON, &header)
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, err.Error()}
+	}
+	if header.Algorithm != KeyAlgorithmECDHES && header.Algorithm != KeyAlgorithmECDHESA256KW {
+		return nil, &ParseError{SegmentHeader, ErrUnsupportedAlg, fmt.Sprintf("alg %q", header.Algorithm)}
+	}
+	if header.Encryption != EncryptionA256GCM {
+		return nil, &ParseError{SegmentHeader, ErrUnsupportedAlg, fmt.Sprintf("enc %q", header.Encryption)}
+	}
+	if header.EphemeralPublicKey == nil {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, "missing epk"}
+	}
+
+	pj := &parsedJWE{segments: segments, header: &header}
+	for i, decoded := range []struct {
+		segment string
+		dst     *[]byte
+		size    int
+	}{
+		{SegmentEncryptedKey, &pj.encryptedKey, -1},
+		{SegmentIV, &pj.iv, gcmIVSize},
+		{SegmentCiphertext, &pj.ciphertext, -1},
+		{SegmentTag, &pj.tag, gcmTagSize},
+	} {
+		*decoded.dst, err = rawEncoding.DecodeString(segments[i+1])
+		if err != nil {
+			return nil, &ParseError{decoded.segment, ErrBadEncoding, err.Error()}
+		}
+		if decoded.size != -1 && len(*decoded.dst) != decoded.size {
+			return nil, &ParseError{decoded.segment, ErrMalformed, fmt.Sprintf("%d bytes, expected %d", len(*decoded.dst), decoded.size)}
+		}
+	}
+
+	// direct key agreement has no encrypted key, a wrapped A256GCM key is always 40 bytes
+	expectedKeySize := 0
+	if header.Algorithm == KeyAlgorithmECDHESA256KW {
+		expectedKeySize = a256KeySize + 8
+	}
+	if len(pj.encryptedKey) != expectedKeySize {
+		return nil, &ParseError{SegmentEncryptedKey, ErrMalformed, fmt.Sprintf("%d bytes, expected %d", len(pj.encryptedKey), expectedKeySize)}
+	}
+
+	return pj, nil
+}
+
+// deriveECDHESKey runs ECDH between private and public and the Concat KDF of RFC 7518 section 4.6.2.
+func deriveECDHESKey(header *JWEHeader, private *ecdsa.PrivateKey, public *ecdsa.PublicKey) ([]byte, error) {
+	if !public.Curve.IsOnCurve(public.X, public.Y) {
+		return nil, errors.New("public key is not on the curve")
+	}
+	// crypto/ecdh and the ECDH() conversion of ecdsa keys need Go 1.20, switch to them once the
+	// module requires it, until then the IsOnCurve check above guards the deprecated ScalarMult
+	//lint:ignore SA1019 go.mod still allows Go 1.18, which has no crypto/ecdh
+	sharedX, _ := public.Curve.ScalarMult(public.X, public.Y, private.D.Bytes())
+	sharedSecret := sharedX.FillBytes(make([]byte, curveKeySize(public.Curve)))
+
+	apu, err := rawEncoding.DecodeString(header.PartyUInfo)
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrBadEncoding, "apu: " + err.Error()}
+	}
+	apv, err := rawEncoding.DecodeString(header.PartyVInfo)
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrBadEncoding, "apv: " + err.Error()}
+	}
+
+	// the algorithm id is enc for direct key agreement and alg when the agreed key wraps the content key
+	algorithmID := header.Encryption
+	if header.Algorithm != KeyAlgorithmECDHES {
+		algorithmID = header.Algorithm
+	}
+
+	return concatKDF(sharedSecret, algorithmID, apu, apv, a256KeySize), nil
+}
+
+func concatKDF(sharedSecret []byte, algorithmID string, apu []byte, apv []byte, keySize int) []byte {
+	otherInfo := lengthPrefixed([]byte(algorithmID))
+	otherInfo = append(otherInfo, lengthPrefixed(apu)...)
+	otherInfo = append(otherInfo, lengthPrefixed(apv)...)
+	otherInfo = append(otherInfo, uint32Bytes(uint32(keySize*8))...)
+
+	var derived []byte
+	for counter := uint32(1); len(derived) < keySize; counter++ {
+		hasher := sha256.New()
+		hasher.Write(uint32Bytes(counter))
+		hasher.Write(sharedSecret)
+		hasher.Write(otherInfo)
+		derived = hasher.Sum(derived)
+	}
+
+	return derived[:keySize]
+}
+
+func lengthPrefixed(data []byte) []byte {
+	return append(uint32Bytes(uint32(len(data))), data...)
+}
+
+func uint32Bytes(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// aesKeyWrap is the RFC 3394 key wrap with the default initial value.
+func aesKeyWrap(kek []byte, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("key to wrap must be a multiple of 8 bytes and at least 16 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	wrapped := make([]byte, 8+len(key))
+	copy(wrapped, keyWrapIV)
+	copy(wrapped[8:], key)
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, wrapped[:8])
+			copy(buf[8:], wrapped[i*8:i*8+8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(wrapped[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(wrapped[i*8:i*8+8], buf[8:])
+		}
+	}
+
+	return wrapped, nil
+}
+
+func aesKeyUnwrap(kek []byte, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("wrapped key must be a multiple of 8 bytes and at least 24 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	unwrapped := make([]byte, len(wrapped))
+	copy(unwrapped, wrapped)
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(unwrapped[:8])^t)
+			copy(buf[8:], unwrapped[i*8:i*8+8])
+			block.Decrypt(buf, buf)
+			copy(unwrapped[:8], buf[:8])
+			copy(unwrapped[i*8:i*8+8], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(unwrapped[:8], keyWrapIV) != 1 {
+		return nil, errors.New("key unwrap integrity check failed")
+	}
+
+	return unwrapped[8:], nil
+}
@@ -0,0 +1,94 @@
+package jwtkit_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestJWE(t *testing.T) {
+	recipient := newTestECDSA(t)
+	other := newTestECDSA(t)
+
+	for _, alg := range []string{jwtkit.KeyAlgorithmECDHES, jwtkit.KeyAlgorithmECDHESA256KW} {
+		encrypter, err := jwtkit.NewEncrypter(alg, recipient)
+		if err != nil {
+			t.Fatalf("%s error: %v", alg, err)
+		}
+		plaintext := []byte(`{"tenant":"acme","email":"someone@example.com"}`)
+		j, err := encrypter.Encrypt(plaintext, "")
+		if err != nil {
+			t.Fatalf("%s error: %v", alg, err)
+		}
+		if strings.Count(string(j), ".") != 4 || strings.Contains(string(j), "acme") {
+			t.Fatalf("%s expected 5 opaque segments got: %s", alg, j)
+		}
+
+		header, decrypted, err := (&jwtkit.Decrypter{Keys: recipient}).Decrypt(j)
+		if err != nil || !bytes.Equal(decrypted, plaintext) || header.Algorithm != alg {
+			t.Fatalf("%s expected round trip got: %s %v", alg, decrypted, err)
+		}
+
+		jwtParts := strings.Split(string(j), ".")
+		tampered := "A"
+		if jwtParts[3][0] == 'A' {
+			tampered = "B"
+		}
+		jwtParts[3] = tampered + jwtParts[3][1:]
+		testCases := []struct {
+			name     string
+			keys     jwtkit.KeyProvider
+			token    jwtkit.JWTString
+			expected error
+		}{
+			{"wrong key", other, j, jwtkit.ErrDecryption},
+			{"tampered ciphertext", recipient, jwtkit.JWTString(strings.Join(jwtParts, ".")), jwtkit.ErrDecryption},
+			{"jws", recipient, jwtkit.JWTString(strings.Join(jwtParts[:3], ".")), jwtkit.ErrMalformed},
+		}
+		for _, tc := range testCases {
+			_, _, err := (&jwtkit.Decrypter{Keys: tc.keys}).Decrypt(tc.token)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("%s %s expected %v got: %v", alg, tc.name, tc.expected, err)
+			}
+		}
+	}
+}
+
+func TestNestedJWT(t *testing.T) {
+	signing := newTestECDSA(t)
+	encryption := newTestECDSA(t)
+
+	signer, err := signing.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	encrypter, err := jwtkit.NewEncrypter(jwtkit.KeyAlgorithmECDHESA256KW, encryption)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	j, err := encrypter.EncryptSigned(&jwtkit.Issuer{Signer: signer, Issuer: "issuer", Expiration: 60000}, "subject", map[string]interface{}{"tenant": "acme"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	verifier := &jwtkit.NestedJWTVerifier{
+		Decrypter: &jwtkit.Decrypter{Keys: encryption},
+		Verifier:  &jwtkit.JWTVerifier{Resolver: signing, Validator: &jwtkit.Validator{Issuer: "issuer"}},
+	}
+	jwt, err := verifier.VerifyToken(j)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if jwt.Payload.Subject != "subject" || jwt.Payload.Claims["tenant"] != "acme" {
+		t.Fatalf("unexpected payload: %+v", jwt.Payload)
+	}
+
+	verifier.Verifier = &jwtkit.JWTVerifier{Resolver: encryption}
+	_, err = verifier.VerifyToken(j)
+	if !errors.Is(err, jwtkit.ErrInvalidSignature) {
+		t.Fatalf("expected the inner signature to be checked got: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package jwtkit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+const JWKSPath = "/.well-known/jwks.json"
+
+const (
+	KeyTypeEC  = "EC"
+	KeyTypeRSA = "RSA"
+	KeyTypeOKP = "OKP"
+)
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK only ever exports the public part of the key, private keys are accepted for convenience.
+func NewJWK(kid string, alg string, key interface{}) (*JWK, error) {
+	jwk := &JWK{KeyID: kid, Use: "sig", Algorithm: alg}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return NewJWK(kid, alg, &k.PublicKey)
+	case *ecdsa.PublicKey:
+		keySize := curveKeySize(k.Curve)
+		jwk.KeyType = KeyTypeEC
+		jwk.Curve = k.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, keySize)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, keySize)))
+	case *rsa.PrivateKey:
+		return NewJWK(kid, alg, &k.PublicKey)
+	case *rsa.PublicKey:
+		jwk.KeyType = KeyTypeRSA
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PrivateKey:
+		return NewJWK(kid, alg, k.Public())
+	case ed25519.PublicKey:
+		jwk.KeyType = KeyTypeOKP
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	case crypto.Signer:
+		return NewJWK(kid, alg, k.Public())
+	default:
+		return nil, fmt.Errorf("%w: %T can not be published as JWK", ErrAlgorithmKeyMismatch, key)
+	}
+
+	return jwk, nil
+}
+
+func (jwk *JWK) PublicKey() (interface{}, error) {
+	switch jwk.KeyType {
+	case KeyTypeEC:
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported JWK curve %q", jwk.Curve)
+		}
+		x, err := decodeJWKCoordinate(jwk.X, curveKeySize(curve))
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKCoordinate(jwk.Y, curveKeySize(curve))
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("JWK point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case KeyTypeRSA:
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA JWK")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case KeyTypeOKP:
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported JWK curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 JWK")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type %q", jwk.KeyType)
+	}
+}
+
+func decodeJWKCoordinate(coordinate string, keySize int) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(coordinate)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != keySize {
+		return nil, errors.New("invalid EC JWK coordinate length")
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// JWKS exports the public keys of every non retired key, HMAC secrets are never exported.
+func (ks *KeySet) JWKS() (*JWKS, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := &JWKS{Keys: []JWK{}}
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		if key.retired {
+			continue
+		}
+		if _, symmetric := key.key.([]byte); symmetric {
+			continue
+		}
+		jwk, err := NewJWK(key.id, key.alg, key.key)
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, *jwk)
+	}
+
+	return jwks, nil
+}
+
+func (ks *KeySet) MarshalJWKS() ([]byte, error) {
+	jwks, err := ks.JWKS()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwks)
+}
+
+// ParseJWKS imports a JWKS document into a verification only key set.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var jwks JWKS
+	err := json.Unmarshal(data, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := NewKeySet()
+	for i := range jwks.Keys {
+		if jwks.Keys[i].Use != "" && jwks.Keys[i].Use != "sig" {
+			continue
+		}
+		publicKey, err := jwks.Keys[i].PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("kid %q: %w", jwks.Keys[i].KeyID, err)
+		}
+		alg := jwks.Keys[i].Algorithm
+		if alg == "" {
+			alg, err = AlgorithmFromKey(publicKey)
+			if err != nil {
+				return nil, fmt.Errorf("kid %q: %w", jwks.Keys[i].KeyID, err)
+			}
+		}
+		err = ks.AddWithAlgorithm(jwks.Keys[i].KeyID, alg, publicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+type jwksHandler struct {
+	ks *KeySet
+}
+
+// JWKSHandler serves the key set as a JWKS document, mount it at JWKSPath.
+func (ks *KeySet) JWKSHandler() http.Handler {
+	return &jwksHandler{ks}
+}
+
+func (jh *jwksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	jwksJSON, err := jh.ks.MarshalJWKS()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(jwksJSON)
+	}
+}
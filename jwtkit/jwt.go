@@ -9,7 +9,21 @@ import (
 	"errors"
 	"math/big"
 	"strings"
-	"time"
+)
+
+const (
+	AlgorithmLegacyECDSA = "ECDSA"
+	AlgorithmHS256       = "HS256"
+	AlgorithmHS384       = "HS384"
+	AlgorithmHS512       = "HS512"
+	AlgorithmRS256       = "RS256"
+	AlgorithmRS384       = "RS384"
+	AlgorithmRS512       = "RS512"
+	AlgorithmPS256       = "PS256"
+	AlgorithmES256       = "ES256"
+	AlgorithmES384       = "ES384"
+	AlgorithmES512       = "ES512"
+	AlgorithmEdDSA       = "EdDSA"
 )
 
 type JWTString string
@@ -23,12 +37,26 @@ type JWT struct {
 type Header struct {
 	Algorithm string `json:"alg"`
 	Type      string `json:"typ"`
+	KeyID     string `json:"kid,omitempty"`
+	// JWK is only set on DPoP proofs, never resolve a verifier from it for other tokens
+	JWK *JWK `json:"jwk,omitempty"`
+}
+
+func (h *Header) format() TokenFormat {
+	if h.Algorithm == AlgorithmLegacyECDSA {
+		return FormatLegacy
+	}
+	return FormatStandard
 }
 
 type Payload struct {
-	Id        string                 `json:"jti,omitempty"`
-	Audience  string                 `json:"aud,omitempty"`
+	Id string `json:"jti,omitempty"`
+	// Audience is the only aud value, or the first one when the token has several
+	Audience string `json:"-"`
+	// Audiences holds every aud value, it wins over Audience when both are set
+	Audiences Audience               `json:"aud,omitempty"`
 	Issuer    string                 `json:"iss,omitempty"`
+	Subject   string                 `json:"sub,omitempty"`
 	IssuedAt  int64                  `json:"iat,omitempty"`
 	ExpiredAt int64                  `json:"exp,omitempty"`
 	NotBefore int64                  `json:"nbf,omitempty"`
@@ -39,9 +67,62 @@ type Signature struct {
 	Hashed []byte   `json:"hashed"`
 	R      *big.Int `json:"r"`
 	S      *big.Int `json:"s"`
+	// Raw is the decoded signature segment of a standard token, it is never part of a legacy token
+	Raw []byte `json:"-"`
+}
+
+type payloadJSON Payload
+
+func (p Payload) MarshalJSON() ([]byte, error) {
+	pj := payloadJSON(p)
+	pj.Audiences = p.audiences()
+	return json.Marshal(pj)
+}
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	var pj payloadJSON
+	err := json.Unmarshal(data, &pj)
+	if err != nil {
+		return err
+	}
+	*p = Payload(pj)
+	if len(p.Audiences) != 0 {
+		p.Audience = p.Audiences[0]
+	}
+	return nil
+}
+
+func (p *Payload) audiences() Audience {
+	if len(p.Audiences) != 0 {
+		return p.Audiences
+	}
+	return NewAudience(p.Audience)
 }
 
 func (je JWTExpiration) GenerateSignedJWTString(encrypt *ECDSA, audience string, issuer string, claims ...*map[string]interface{}) (JWTString, error) {
+	return (&LegacyIssuer{ECDSA: encrypt, Expiration: je}).Issue(audience, issuer, claims...)
+}
+
+// LegacyIssuer generates the legacy toolkit format, GenerateSignedJWTString is a LegacyIssuer with
+// the SystemClock and milliseconds.
+type LegacyIssuer struct {
+	ECDSA *ECDSA
+	// Expiration is always given in milliseconds, whatever unit the token uses
+	Expiration JWTExpiration
+	Clock      Clock
+	// Seconds issues NumericDate seconds, the zero value keeps the legacy milliseconds
+	Seconds bool
+}
+
+func (li *LegacyIssuer) timeUnit() TimeUnit {
+	if li.Seconds {
+		return Seconds
+	}
+	return Milliseconds
+}
+
+func (li *LegacyIssuer) Issue(audience string, issuer string, claims ...*map[string]interface{}) (JWTString, error) {
+	encrypt := li.ECDSA
 	privateKey, err := GetPrivateFromPEM(encrypt)
 	if err != nil {
 		return "", err
@@ -50,17 +131,24 @@ func (je JWTExpiration) GenerateSignedJWTString(encrypt *ECDSA, audience string,
 	if err != nil {
 		return "", err
 	}
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
 
+	unit := li.timeUnit()
+	now := unit.now(li.Clock)
 	jwt := &JWT{
 		Header: &Header{
-			Algorithm: "ECDSA",
+			Algorithm: AlgorithmLegacyECDSA,
 			Type:      "JWT",
 		},
 		Payload: &Payload{
+			Id:        jti,
 			Audience:  audience,
 			Issuer:    issuer,
-			IssuedAt:  time.Now().UnixNano() / 1000000,
-			ExpiredAt: (time.Now().UnixNano() / 1000000) + int64(je),
+			IssuedAt:  now,
+			ExpiredAt: now + li.Expiration.in(unit),
 		},
 	}
 
@@ -139,31 +227,30 @@ func VerifyJWTString(encrypt *ECDSA, j JWTString) (bool, error) {
 		return false, err
 	}
 
-	jwtParts := strings.Split(string(j), ".")
-
-	hashBase := jwtParts[0] + "." + jwtParts[1]
-
-	hasher := sha256.New()
-	hasher.Write([]byte(hashBase))
-	supposedHashed := hasher.Sum(nil)
-
-	decodedSignature, err := base64.URLEncoding.DecodeString(jwtParts[2])
+	pt, err := parseToken(j)
 	if err != nil {
 		return false, err
 	}
+	if pt.format != FormatLegacy {
+		return verifyStandardJWTString(encrypt, pt)
+	}
+
+	hasher := sha256.New()
+	hasher.Write(pt.signingInput())
+	supposedHashed := hasher.Sum(nil)
 
 	var signature Signature
-	err = json.Unmarshal([]byte(decodedSignature), &signature)
+	err = json.Unmarshal(pt.signature, &signature)
 	if err != nil {
-		return false, err
+		return false, &ParseError{SegmentSignature, ErrMalformed, err.Error()}
 	}
 
-	if string(supposedHashed) != string(signature.Hashed) {
-		return false, errors.New("invalid signature")
+	if string(supposedHashed) != string(signature.Hashed) || signature.R == nil || signature.S == nil {
+		return false, ErrInvalidSignature
 	}
 
 	if !ecdsa.Verify(publicKey, signature.Hashed, signature.R, signature.S) {
-		return false, err
+		return false, ErrInvalidSignature
 	}
 
 	signature.Hashed[0] ^= 0xff
@@ -175,70 +262,73 @@ func VerifyJWTString(encrypt *ECDSA, j JWTString) (bool, error) {
 }
 
 func ValidateExpired(j JWTString) (bool, error) {
-	jwtParts := strings.Split(string(j), ".")
-	decodedPayload, err := base64.URLEncoding.DecodeString(jwtParts[1])
+	return ValidateExpiredAt(SystemClock, j)
+}
+
+// ValidateExpiredAt is ValidateExpired with the current time taken from clock.
+func ValidateExpiredAt(clock Clock, j JWTString) (bool, error) {
+	pt, err := parseToken(j)
 	if err != nil {
 		return false, err
 	}
 
 	var payload Payload
-	err = json.Unmarshal(decodedPayload, &payload)
+	err = json.Unmarshal(pt.payload, &payload)
 	if err != nil {
-		return false, err
+		return false, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
 	}
 
-	if now := time.Now().UnixNano() / 1000000; payload.ExpiredAt <= now {
-
-		return false, err
+	if now := pt.format.TimeUnit().now(clock); payload.ExpiredAt <= now {
+		return false, ErrExpired
 	}
 
 	return true, err
 }
 
+// RegenerateToken only re-signs a token that verifies and is not expired, use RefreshIssuer for refresh tokens.
 func RegenerateToken(encrypt *ECDSA, audience string, issuer string, je JWTExpiration, j JWTString) (JWTString, error) {
-	jwt, err := GetJWT(j)
+	ok, err := VerifyJWTString(encrypt, j)
 	if err != nil {
 		return "", err
 	}
-	return je.GenerateSignedJWTString(encrypt, audience, issuer, &jwt.Payload.Claims)
-}
-
-func GetJWT(j JWTString) (*JWT, error) {
-	jwtParts := strings.Split(string(j), ".")
-
-	decodedHeader, err := base64.URLEncoding.DecodeString(jwtParts[0])
-	if err != nil {
-		return nil, err
+	if !ok {
+		return "", ErrInvalidSignature
 	}
-	decodedPayload, err := base64.URLEncoding.DecodeString(jwtParts[1])
+
+	jwt, err := GetJWT(j)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	decodedSignature, err := base64.URLEncoding.DecodeString(jwtParts[2])
+	err = (&Validator{TimeUnit: jwt.Header.format().TimeUnit()}).Validate(jwt.Payload)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return je.GenerateSignedJWTString(encrypt, audience, issuer, &jwt.Payload.Claims)
+}
 
-	var jwtHeader Header
-	err = json.Unmarshal(decodedHeader, &jwtHeader)
+func GetJWT(j JWTString) (*JWT, error) {
+	pt, err := parseToken(j)
 	if err != nil {
 		return nil, err
 	}
+	if pt.format != FormatLegacy {
+		return getStandardJWT(pt)
+	}
 
 	var jwtPayload Payload
-	err = json.Unmarshal(decodedPayload, &jwtPayload)
+	err = json.Unmarshal(pt.payload, &jwtPayload)
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
 	}
 
 	var jwtSignature Signature
-	err = json.Unmarshal(decodedSignature, &jwtSignature)
+	err = json.Unmarshal(pt.signature, &jwtSignature)
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{SegmentSignature, ErrMalformed, err.Error()}
 	}
 
 	return &JWT{
-		Header:    &jwtHeader,
+		Header:    pt.header,
 		Payload:   &jwtPayload,
 		Signature: &jwtSignature,
 	}, nil
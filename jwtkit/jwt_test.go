@@ -0,0 +1,200 @@
+package jwtkit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func newTestECDSA(t testing.TB) *jwtkit.ECDSA {
+	dir := t.TempDir()
+	encrypt := &jwtkit.ECDSA{
+		PublicKeyPath:  filepath.Join(dir, "public.pem"),
+		PrivateKeyPath: filepath.Join(dir, "private.pem"),
+	}
+	err := jwtkit.GeneratePublicPrivateToPEM(encrypt)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return encrypt
+}
+
+func TestGenerateStandardJWTString(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	claims := map[string]interface{}{"role": "admin"}
+
+	j, err := jwtkit.JWTExpiration(60000).GenerateStandardJWTString(encrypt, "audience", "issuer", &claims)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	jwtParts := strings.Split(string(j), ".")
+	if len(jwtParts) != 3 || strings.ContainsRune(string(j), '=') {
+		t.Fatalf("expected unpadded compact serialization got: %s", j)
+	}
+
+	decodedHeader, err := base64.RawURLEncoding.DecodeString(jwtParts[0])
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if string(decodedHeader) != `{"alg":"ES256","typ":"JWT"}` {
+		t.Fatalf("unexpected header: %s", decodedHeader)
+	}
+
+	decodedPayload, err := base64.RawURLEncoding.DecodeString(jwtParts[1])
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	var flat map[string]interface{}
+	err = json.Unmarshal(decodedPayload, &flat)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if flat["role"] != "admin" || flat["aud"] != "audience" || flat["claims"] != nil {
+		t.Fatalf("expected flattened claims got: %s", decodedPayload)
+	}
+	// iat and exp are NumericDate seconds, as any RFC 7519 consumer reads them
+	exp, _ := flat["exp"].(float64)
+	if expiredAt := time.Unix(int64(exp), 0); expiredAt.Before(time.Now().Add(50*time.Second)) || expiredAt.After(time.Now().Add(70*time.Second)) {
+		t.Fatalf("expected exp one minute from now in seconds got: %v", flat["exp"])
+	}
+
+	// verify with nothing but the standard algorithm to make sure other libraries can too
+	signature, err := base64.RawURLEncoding.DecodeString(jwtParts[2])
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(signature) != 64 {
+		t.Fatalf("expected 64 bytes signature got: %d", len(signature))
+	}
+	publicKey, err := jwtkit.GetPublicFromPEM(encrypt)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	hashed := sha256.Sum256([]byte(jwtParts[0] + "." + jwtParts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(publicKey, hashed[:], r, s) {
+		t.Fatalf("raw R||S signature fails to verify")
+	}
+
+	ok, err := jwtkit.VerifyJWTString(encrypt, j)
+	if !ok || err != nil {
+		t.Fatalf("expected valid got: %t %v", ok, err)
+	}
+
+	jwt, err := jwtkit.GetJWT(j)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if jwt.Header.Algorithm != jwtkit.AlgorithmES256 || jwt.Payload.Audience != "audience" || jwt.Payload.Claims["role"] != "admin" {
+		t.Fatalf("unexpected jwt: %+v %+v", jwt.Header, jwt.Payload)
+	}
+
+	tampered := jwtkit.JWTString(jwtParts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"role":"root"}`)) + "." + jwtParts[2])
+	ok, err = jwtkit.VerifyJWTString(encrypt, tampered)
+	if ok || err == nil {
+		t.Fatalf("expected tampered token to fail got: %t %v", ok, err)
+	}
+}
+
+func TestGenerateSignedJWTString(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	claims := map[string]interface{}{"role": "admin"}
+
+	j, err := jwtkit.JWTExpiration(60000).GenerateSignedJWTString(encrypt, "audience", "issuer", &claims)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	ok, err := jwtkit.VerifyJWTString(encrypt, j)
+	if !ok || err != nil {
+		t.Fatalf("expected valid got: %t %v", ok, err)
+	}
+
+	jwt, err := jwtkit.GetJWT(j)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if jwt.Header.Algorithm != jwtkit.AlgorithmLegacyECDSA || jwt.Payload.Claims["role"] != "admin" {
+		t.Fatalf("unexpected jwt: %+v %+v", jwt.Header, jwt.Payload)
+	}
+}
+
+func TestGeneratorsClock(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	signer, err := encrypt.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	clock := jwtkit.NewFrozenClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	standard := jwtkit.JWTExpiration(60000).StandardIssuer(signer, "audience", "issuer")
+	standard.Clock = clock
+	legacySeconds := &jwtkit.LegacyIssuer{ECDSA: encrypt, Expiration: 60000, Clock: clock, Seconds: true}
+	legacy := &jwtkit.LegacyIssuer{ECDSA: encrypt, Expiration: 60000, Clock: clock}
+
+	testCases := []struct {
+		name     string
+		issue    func() (jwtkit.JWTString, error)
+		expected int64
+		lifetime int64
+	}{
+		{"standard", func() (jwtkit.JWTString, error) { return standard.Issue("", nil) }, clock.Now().Unix(), 60},
+		{"legacy seconds", func() (jwtkit.JWTString, error) { return legacySeconds.Issue("audience", "issuer") }, clock.Now().Unix(), 60},
+		{"legacy", func() (jwtkit.JWTString, error) { return legacy.Issue("audience", "issuer") }, clock.Now().UnixMilli(), 60000},
+	}
+	for _, tc := range testCases {
+		j, err := tc.issue()
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		jwt, err := jwtkit.GetJWT(j)
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		if jwt.Payload.IssuedAt != tc.expected || jwt.Payload.ExpiredAt != tc.expected+tc.lifetime {
+			t.Fatalf("%s expected iat %d exp %d got: %+v", tc.name, tc.expected, tc.expected+tc.lifetime, jwt.Payload)
+		}
+	}
+}
+
+func TestLegacyHelpersTimeUnit(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	legacyToken, err := jwtkit.JWTExpiration(60000).GenerateSignedJWTString(encrypt, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	standardToken, err := jwtkit.JWTExpiration(60000).GenerateStandardJWTString(encrypt, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	later := jwtkit.NewFrozenClock(time.Now().Add(2 * time.Minute))
+
+	for _, j := range []jwtkit.JWTString{legacyToken, standardToken} {
+		format, err := jwtkit.DetectFormat(j)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if ok, err := jwtkit.ValidateExpired(j); !ok || err != nil {
+			t.Fatalf("%s ValidateExpired expected valid got: %t %v", format, ok, err)
+		}
+		if ok, err := jwtkit.ValidateExpiredAt(later, j); ok || !errors.Is(err, jwtkit.ErrExpired) {
+			t.Fatalf("%s ValidateExpiredAt expected expired got: %t %v", format, ok, err)
+		}
+		if _, err = (&jwtkit.LegacyJWTVerifier{ECDSA: encrypt}).VerifyToken(j); err != nil {
+			t.Fatalf("%s LegacyJWTVerifier expected valid got: %v", format, err)
+		}
+		if _, err = jwtkit.RegenerateToken(encrypt, "audience", "issuer", 60000, j); err != nil {
+			t.Fatalf("%s RegenerateToken expected a new token got: %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package jwtkit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PEMTypePrivateKey       = "PRIVATE KEY"
+	PEMTypeECPrivateKey     = "EC PRIVATE KEY"
+	PEMTypeRSAPrivateKey    = "RSA PRIVATE KEY"
+	PEMTypePublicKey        = "PUBLIC KEY"
+	PEMTypeRSAPublicKey     = "RSA PUBLIC KEY"
+	PEMTypeCertificate      = "CERTIFICATE"
+	PEMTypeLegacyPrivateKey = "E256 PRIVATE KEY"
+	PEMTypeLegacyPublicKey  = "E256 PUBLIC KEY"
+)
+
+var ErrNoKey = errors.New("key provider has no such key")
+
+type KeyProvider interface {
+	PrivateKey() (crypto.PrivateKey, error)
+	PublicKey() (crypto.PublicKey, error)
+}
+
+// ParsePrivateKeyPEM accepts PKCS#8, SEC1, PKCS#1 and the legacy E256 PEM blocks.
+func ParsePrivateKeyPEM(data []byte) (crypto.PrivateKey, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case PEMTypePrivateKey:
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		case PEMTypeECPrivateKey, PEMTypeLegacyPrivateKey:
+			return x509.ParseECPrivateKey(block.Bytes)
+		case PEMTypeRSAPrivateKey:
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		}
+	}
+	return nil, errors.New("failed to decode PEM block containing private key")
+}
+
+// ParsePublicKeyPEM accepts PKIX, PKCS#1, X.509 certificates and the legacy E256 PEM blocks.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case PEMTypePublicKey, PEMTypeLegacyPublicKey:
+			publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				if cert, certErr := x509.ParseCertificate(block.Bytes); certErr == nil {
+					return cert.PublicKey, nil
+				}
+				return nil, err
+			}
+			return publicKey, nil
+		case PEMTypeRSAPublicKey:
+			return x509.ParsePKCS1PublicKey(block.Bytes)
+		case PEMTypeCertificate:
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return cert.PublicKey, nil
+		}
+	}
+	return nil, errors.New("failed to decode PEM block containing public key")
+}
+
+// GenerateKey generates a fresh key for alg, HMAC secrets are as long as the hash output.
+func GenerateKey(alg string) (crypto.PrivateKey, error) {
+	switch alg {
+	case AlgorithmES256, AlgorithmES384, AlgorithmES512:
+		return ecdsa.GenerateKey(algorithmCurve(alg), rand.Reader)
+	case AlgorithmRS256, AlgorithmRS384, AlgorithmRS512, AlgorithmPS256:
+		return rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	case AlgorithmEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	case AlgorithmHS256, AlgorithmHS384, AlgorithmHS512:
+		hash, _ := algorithmHash(alg)
+		secret := make([]byte, hash.Size())
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+	}
+}
+
+// MarshalPrivateKeyPEM encodes the key as a PKCS#8 "PRIVATE KEY" block.
+func MarshalPrivateKeyPEM(privateKey crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PEMTypePrivateKey, Bytes: der}), nil
+}
+
+// MarshalPublicKeyPEM encodes the key as a PKIX "PUBLIC KEY" block.
+func MarshalPublicKeyPEM(publicKey crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PEMTypePublicKey, Bytes: der}), nil
+}
+
+func publicFromPrivate(privateKey crypto.PrivateKey) (crypto.PublicKey, error) {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T has no public key", ErrAlgorithmKeyMismatch, privateKey)
+	}
+	return signer.Public(), nil
+}
+
+// StaticKeyProvider holds keys that never change, the public key is derived from the private key when not given.
+// A []byte private key is a symmetric secret and has no public key.
+type StaticKeyProvider struct {
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+func NewStaticKeyProvider(privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (*StaticKeyProvider, error) {
+	if privateKey == nil && publicKey == nil {
+		return nil, ErrNoKey
+	}
+	if _, symmetric := privateKey.([]byte); publicKey == nil && !symmetric {
+		var err error
+		publicKey, err = publicFromPrivate(privateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &StaticKeyProvider{privateKey: privateKey, publicKey: publicKey}, nil
+}
+
+// NewKeyProviderFromSigner accepts any crypto.Signer such as a KMS or HSM backed key.
+func NewKeyProviderFromSigner(signer crypto.Signer) *StaticKeyProvider {
+	return &StaticKeyProvider{privateKey: signer, publicKey: signer.Public()}
+}
+
+// NewKeyProviderFromPEM parses PEM bytes, either of them may be nil.
+func NewKeyProviderFromPEM(privatePEM []byte, publicPEM []byte) (*StaticKeyProvider, error) {
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+	var err error
+
+	if len(privatePEM) != 0 {
+		privateKey, err = ParsePrivateKeyPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(publicPEM) != 0 {
+		publicKey, err = ParsePublicKeyPEM(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewStaticKeyProvider(privateKey, publicKey)
+}
+
+// NewKeyProviderFromEnv reads PEM from the named environment variables, the value may also be
+// base64 encoded PEM for environments that can not hold newlines. An empty name is skipped.
+func NewKeyProviderFromEnv(privateKeyEnv string, publicKeyEnv string) (*StaticKeyProvider, error) {
+	privatePEM, err := pemFromEnv(privateKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+	publicPEM, err := pemFromEnv(publicKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyProviderFromPEM(privatePEM, publicPEM)
+}
+
+func pemFromEnv(name string) ([]byte, error) {
+	if name == "" {
+		return nil, nil
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s is neither PEM nor base64 PEM", name)
+	}
+	return decoded, nil
+}
+
+func (skp *StaticKeyProvider) PrivateKey() (crypto.PrivateKey, error) {
+	if skp.privateKey == nil {
+		return nil, ErrNoKey
+	}
+	return skp.privateKey, nil
+}
+
+func (skp *StaticKeyProvider) PublicKey() (crypto.PublicKey, error) {
+	if skp.publicKey == nil {
+		return nil, ErrNoKey
+	}
+	return skp.publicKey, nil
+}
+
+// cachedKeyFile parses a key file once and only parses it again when its size or modification time changes.
+type cachedKeyFile struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	size    int64
+	key     interface{}
+}
+
+func (ckf *cachedKeyFile) load(path string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	ckf.mu.Lock()
+	defer ckf.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if ckf.key != nil && ckf.path == path && ckf.modTime.Equal(info.ModTime()) && ckf.size == info.Size() {
+		return ckf.key, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	ckf.path, ckf.modTime, ckf.size, ckf.key = path, info.ModTime(), info.Size(), key
+
+	return key, nil
+}
+
+// FileKeyProvider reads PEM files and reloads them whenever they change on disk.
+type FileKeyProvider struct {
+	PrivateKeyPath string
+	PublicKeyPath  string
+	private        cachedKeyFile
+	public         cachedKeyFile
+}
+
+func (fkp *FileKeyProvider) PrivateKey() (crypto.PrivateKey, error) {
+	if fkp.PrivateKeyPath == "" {
+		return nil, ErrNoKey
+	}
+	return fkp.private.load(fkp.PrivateKeyPath, func(data []byte) (interface{}, error) {
+		return ParsePrivateKeyPEM(data)
+	})
+}
+
+func (fkp *FileKeyProvider) PublicKey() (crypto.PublicKey, error) {
+	if fkp.PublicKeyPath == "" {
+		privateKey, err := fkp.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		return publicFromPrivate(privateKey)
+	}
+	return fkp.public.load(fkp.PublicKeyPath, func(data []byte) (interface{}, error) {
+		return ParsePublicKeyPEM(data)
+	})
+}
+
+type providerSigner struct {
+	kp  KeyProvider
+	alg string
+}
+
+// ProviderSigner signs with whatever private key the provider currently holds, alg is taken from
+// the key when empty and stays fixed afterwards.
+func ProviderSigner(kp KeyProvider, alg string) (Signer, error) {
+	privateKey, err := kp.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if alg == "" {
+		alg, err = AlgorithmFromKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = NewSigner(alg, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &providerSigner{kp: kp, alg: alg}, nil
+}
+
+func (ps *providerSigner) Algorithm() string { return ps.alg }
+
+func (ps *providerSigner) Sign(signingInput []byte) ([]byte, error) {
+	privateKey, err := ps.kp.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewSigner(ps.alg, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(signingInput)
+}
+
+// ProviderVerifierResolver verifies with the public key of the provider, or its secret when the
+// provider holds an HMAC secret.
+func ProviderVerifierResolver(kp KeyProvider) VerifierResolver {
+	return VerifierResolverFunc(func(header *Header) (Verifier, error) {
+		publicKey, err := kp.PublicKey()
+		if errors.Is(err, ErrNoKey) {
+			privateKey, privateErr := kp.PrivateKey()
+			if secret, ok := privateKey.([]byte); ok && privateErr == nil {
+				return NewVerifier(header.Algorithm, secret)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewVerifier(header.Algorithm, publicKey)
+	})
+}
@@ -0,0 +1,150 @@
+package jwtkit_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func newTestPEM(t *testing.T) (*ecdsa.PrivateKey, []byte, []byte) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return privateKey,
+		pem.EncodeToMemory(&pem.Block{Type: jwtkit.PEMTypePrivateKey, Bytes: pkcs8}),
+		pem.EncodeToMemory(&pem.Block{Type: jwtkit.PEMTypePublicKey, Bytes: pkix})
+}
+
+// opaqueSigner hides the concrete key the way a KMS or HSM backed crypto.Signer would.
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestKeyProviders(t *testing.T) {
+	privateKey, privatePEM, publicPEM := newTestPEM(t)
+
+	t.Setenv("JWTKIT_TEST_PRIVATE", base64.StdEncoding.EncodeToString(privatePEM))
+	t.Setenv("JWTKIT_TEST_PUBLIC", string(publicPEM))
+	envProvider, err := jwtkit.NewKeyProviderFromEnv("JWTKIT_TEST_PRIVATE", "JWTKIT_TEST_PUBLIC")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	pemProvider, err := jwtkit.NewKeyProviderFromPEM(privatePEM, nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		provider jwtkit.KeyProvider
+	}{
+		{"pem", pemProvider},
+		{"env", envProvider},
+		{"crypto.Signer", jwtkit.NewKeyProviderFromSigner(&opaqueSigner{privateKey})},
+	}
+
+	for _, tc := range testCases {
+		signer, err := jwtkit.ProviderSigner(tc.provider, "")
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		if signer.Algorithm() != jwtkit.AlgorithmES256 {
+			t.Fatalf("%s expected ES256 got: %s", tc.name, signer.Algorithm())
+		}
+		j, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		ok, err := jwtkit.VerifyJWTStringWith(jwtkit.KeyVerifierResolver(&privateKey.PublicKey), j)
+		if err != nil || !ok {
+			t.Fatalf("%s expected valid signature got: %v", tc.name, err)
+		}
+	}
+}
+
+func TestFileKeyProviderReload(t *testing.T) {
+	dir := t.TempDir()
+	fkp := &jwtkit.FileKeyProvider{PrivateKeyPath: filepath.Join(dir, "private.pem")}
+
+	firstKey, firstPEM, _ := newTestPEM(t)
+	err := ioutil.WriteFile(fkp.PrivateKeyPath, firstPEM, 0600)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	publicKey, err := fkp.PublicKey()
+	if err != nil || !firstKey.PublicKey.Equal(publicKey) {
+		t.Fatalf("expected first public key got: %v", err)
+	}
+
+	secondKey, secondPEM, _ := newTestPEM(t)
+	err = ioutil.WriteFile(fkp.PrivateKeyPath, secondPEM, 0600)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	modTime := time.Now().Add(time.Second)
+	err = os.Chtimes(fkp.PrivateKeyPath, modTime, modTime)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	publicKey, err = fkp.PublicKey()
+	if err != nil || !secondKey.PublicKey.Equal(publicKey) {
+		t.Fatalf("expected rotated public key got: %v", err)
+	}
+}
+
+func TestECDSAKeyCache(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	first, err := encrypt.PrivateKey()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	copied := *encrypt
+	for _, kp := range []jwtkit.KeyProvider{encrypt, &copied} {
+		privateKey, err := kp.PrivateKey()
+		if err != nil || privateKey != first {
+			t.Fatalf("expected the cached private key to be reused got: %v", err)
+		}
+	}
+	if _, err = jwtkit.JWTExpiration(60000).GenerateSignedJWTString(encrypt, "audience", "issuer"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if privateKey, _ := encrypt.PrivateKey(); privateKey != first {
+		t.Fatal("expected signing to reuse the cached private key")
+	}
+
+	secondKey, secondPEM, _ := newTestPEM(t)
+	err = ioutil.WriteFile(encrypt.PrivateKeyPath, secondPEM, 0600)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	modTime := time.Now().Add(time.Second)
+	err = os.Chtimes(encrypt.PrivateKeyPath, modTime, modTime)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	privateKey, err := encrypt.PrivateKey()
+	if err != nil || !secondKey.Equal(privateKey) {
+		t.Fatalf("expected the rewritten private key got: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package jwtkit
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrUnknownKeyID = errors.New("unknown key id")
+	ErrKeyRetired   = errors.New("key is retired")
+	ErrNoActiveKey  = errors.New("key set has no active key")
+)
+
+type keySetKey struct {
+	id      string
+	alg     string
+	key     interface{}
+	retired bool
+}
+
+// KeySet signs with its active key and verifies with any non retired key chosen by the kid header,
+// so keys can be rotated without invalidating outstanding tokens.
+type KeySet struct {
+	mu     sync.RWMutex
+	keys   map[string]*keySetKey
+	order  []string
+	active string
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*keySetKey)}
+}
+
+func (ks *KeySet) Add(kid string, key interface{}) error {
+	alg, err := AlgorithmFromKey(key)
+	if err != nil {
+		return err
+	}
+	return ks.AddWithAlgorithm(kid, alg, key)
+}
+
+func (ks *KeySet) AddWithAlgorithm(kid string, alg string, key interface{}) error {
+	if kid == "" {
+		return errors.New("key id must not be empty")
+	}
+	_, err := NewVerifier(alg, key)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, exists := ks.keys[kid]; exists {
+		return fmt.Errorf("key id %q already exists", kid)
+	}
+	ks.keys[kid] = &keySetKey{id: kid, alg: alg, key: key}
+	ks.order = append(ks.order, kid)
+
+	return nil
+}
+
+// Activate makes kid the key new tokens are signed with, the previously active key keeps verifying.
+func (ks *KeySet) Activate(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, kid)
+	}
+	if key.retired {
+		return fmt.Errorf("%w: %q", ErrKeyRetired, kid)
+	}
+	if !isSigningKey(key.key) {
+		return fmt.Errorf("key id %q has no private key", kid)
+	}
+	ks.active = kid
+
+	return nil
+}
+
+// Retire stops kid from verifying and from being published, the active key can not be retired.
+func (ks *KeySet) Retire(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, kid)
+	}
+	if ks.active == kid {
+		return fmt.Errorf("key id %q is active", kid)
+	}
+	key.retired = true
+
+	return nil
+}
+
+func (ks *KeySet) Remove(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.keys[kid]; !ok {
+		return
+	}
+	delete(ks.keys, kid)
+	for i := range ks.order {
+		if ks.order[i] == kid {
+			ks.order = append(ks.order[:i], ks.order[i+1:]...)
+			break
+		}
+	}
+	if ks.active == kid {
+		ks.active = ""
+	}
+}
+
+func (ks *KeySet) ActiveKeyID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Signer returns a signer bound to the currently active key, tokens it signs carry the kid header.
+func (ks *KeySet) Signer() (Signer, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.active == "" {
+		return nil, ErrNoActiveKey
+	}
+	key := ks.keys[ks.active]
+	signer, err := NewSigner(key.alg, key.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return WithKeyID(signer, key.id), nil
+}
+
+func (ks *KeySet) ResolveVerifier(header *Header) (Verifier, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kid := header.KeyID
+	if kid == "" && len(ks.order) == 1 {
+		kid = ks.order[0]
+	}
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, header.KeyID)
+	}
+	if key.retired {
+		return nil, fmt.Errorf("%w: %q", ErrKeyRetired, kid)
+	}
+	if header.Algorithm != key.alg {
+		return nil, fmt.Errorf("%w: key id %q is %s got %q", ErrAlgorithmKeyMismatch, kid, key.alg, header.Algorithm)
+	}
+
+	return NewVerifier(key.alg, key.key)
+}
+
+func isSigningKey(key interface{}) bool {
+	if isConcretePrivateKey(key) {
+		return true
+	}
+	_, ok := key.(crypto.Signer)
+	return ok
+}
+
+// WithKeyID makes the tokens signed by signer carry kid in their header.
+func WithKeyID(signer Signer, kid string) Signer {
+	return &keyIDSigner{Signer: signer, kid: kid}
+}
+
+type keyIDSigner struct {
+	Signer
+	kid string
+}
+
+func (kis *keyIDSigner) KeyID() string { return kis.kid }
@@ -0,0 +1,140 @@
+package jwtkit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	ks := jwtkit.NewKeySet()
+	if err = ks.Add("old", oldKey); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err = ks.Activate("old"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	signer, err := ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	oldToken, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if err = ks.Add("new", newKey); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err = ks.Activate("new"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	signer, err = ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	newToken, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	jwt, err := jwtkit.GetJWT(newToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if jwt.Header.KeyID != "new" || jwt.Header.Algorithm != jwtkit.AlgorithmRS256 {
+		t.Fatalf("unexpected header: %+v", jwt.Header)
+	}
+
+	for _, j := range []jwtkit.JWTString{oldToken, newToken} {
+		ok, err := jwtkit.VerifyJWTStringWith(ks, j)
+		if !ok || err != nil {
+			t.Fatalf("expected valid got: %t %v", ok, err)
+		}
+	}
+
+	if err = ks.Retire("new"); err == nil {
+		t.Fatalf("expected active key to not be retirable")
+	}
+	if err = ks.Retire("old"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	ok, err := jwtkit.VerifyJWTStringWith(ks, oldToken)
+	if ok || !errors.Is(err, jwtkit.ErrKeyRetired) {
+		t.Fatalf("expected retired key got: %t %v", ok, err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	ks := jwtkit.NewKeySet()
+	ks.Add("ec", ecKey)
+	ks.Add("ed", edKey)
+	ks.Add("secret", []byte("a secret that is long enough for HS256"))
+	ks.Activate("ed")
+
+	server := httptest.NewServer(ks.JWKSHandler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + jwtkit.JWKSPath)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	jwksJSON, err := ks.MarshalJWKS()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	imported, err := jwtkit.ParseJWKS(jwksJSON)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	jwks, err := imported.JWKS()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected the secret to never be exported got: %+v", jwks.Keys)
+	}
+
+	signer, err := ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	j, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	ok, err := jwtkit.VerifyJWTStringWith(imported, j)
+	if !ok || err != nil {
+		t.Fatalf("expected valid with imported JWKS got: %t %v", ok, err)
+	}
+}
@@ -0,0 +1,127 @@
+package jwtkit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLegacyTokenRejected = errors.New("legacy tokens are no longer accepted")
+
+type TokenFormat int
+
+const (
+	FormatStandard TokenFormat = iota
+	FormatLegacy
+)
+
+func (tf TokenFormat) String() string {
+	if tf == FormatLegacy {
+		return "legacy"
+	}
+	return "standard"
+}
+
+// TimeUnit is the unit iat, exp and nbf are written in, milliseconds for legacy tokens.
+func (tf TokenFormat) TimeUnit() TimeUnit {
+	if tf == FormatLegacy {
+		return Milliseconds
+	}
+	return Seconds
+}
+
+// DetectFormat tells the legacy toolkit format from RFC 7515 compact JWS by the alg header,
+// nothing is verified.
+func DetectFormat(j JWTString) (TokenFormat, error) {
+	pt, err := parseToken(j)
+	if err != nil {
+		return 0, err
+	}
+	return pt.format, nil
+}
+
+// MigrationVerifier accepts standard tokens and, until LegacyUntil, legacy tokens too, so clients
+// still holding legacy tokens keep working while the issuer already signs standard ones.
+type MigrationVerifier struct {
+	Standard TokenVerifier
+	Legacy   TokenVerifier
+	// LegacyUntil closes the migration window, the zero value keeps it open
+	LegacyUntil time.Time
+	Clock       Clock
+	// OnVerified is called with the format of every successfully verified token to track adoption
+	OnVerified func(format TokenFormat)
+}
+
+func (mv *MigrationVerifier) VerifyToken(j JWTString) (*JWT, error) {
+	jwt, _, err := mv.VerifyTokenFormat(j)
+	return jwt, err
+}
+
+func (mv *MigrationVerifier) VerifyTokenFormat(j JWTString) (*JWT, TokenFormat, error) {
+	format, err := DetectFormat(j)
+	if err != nil {
+		return nil, format, err
+	}
+
+	verifier := mv.Standard
+	if format == FormatLegacy {
+		if mv.Legacy == nil {
+			return nil, format, ErrLegacyTokenRejected
+		}
+		clock := mv.Clock
+		if clock == nil {
+			clock = SystemClock
+		}
+		if !mv.LegacyUntil.IsZero() && !clock.Now().Before(mv.LegacyUntil) {
+			return nil, format, fmt.Errorf("%w: migration window closed at %s", ErrLegacyTokenRejected, mv.LegacyUntil.Format(time.RFC3339))
+		}
+		verifier = mv.Legacy
+	}
+
+	jwt, err := verifier.VerifyToken(j)
+	if err != nil {
+		return nil, format, err
+	}
+	if mv.OnVerified != nil {
+		mv.OnVerified(format)
+	}
+
+	return jwt, format, nil
+}
+
+// ReissueLegacy verifies a legacy token and issues a standard token with the same jti, subject,
+// audience, issuer and custom claims. The token keeps its expiry instant, converted to the
+// issuer's TimeUnit, so migrating never extends its lifetime.
+func ReissueLegacy(legacy *LegacyJWTVerifier, issuer *Issuer, j JWTString) (JWTString, error) {
+	format, err := DetectFormat(j)
+	if err != nil {
+		return "", err
+	}
+	if format != FormatLegacy {
+		return "", errors.New("token is not a legacy token")
+	}
+
+	jwt, err := legacy.VerifyToken(j)
+	if err != nil {
+		return "", err
+	}
+
+	rc := &RegisteredClaims{
+		Id:        jwt.Payload.Id,
+		Audience:  jwt.Payload.audiences(),
+		Issuer:    jwt.Payload.Issuer,
+		Subject:   jwt.Payload.Subject,
+		IssuedAt:  convertTimestamp(jwt.Payload.IssuedAt, Milliseconds, issuer.TimeUnit),
+		ExpiredAt: convertTimestamp(jwt.Payload.ExpiredAt, Milliseconds, issuer.TimeUnit),
+		NotBefore: convertTimestamp(jwt.Payload.NotBefore, Milliseconds, issuer.TimeUnit),
+	}
+
+	return issuer.IssueRegistered(rc, jwt.Payload.Claims)
+}
+
+func convertTimestamp(timestamp int64, from TimeUnit, to TimeUnit) int64 {
+	if timestamp == 0 || from == to {
+		return timestamp
+	}
+	return to.Timestamp(from.Time(timestamp))
+}
@@ -0,0 +1,81 @@
+package jwtkit_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestMigrationVerifier(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	claims := map[string]interface{}{"role": "admin"}
+
+	legacyToken, err := jwtkit.JWTExpiration(60000).GenerateSignedJWTString(encrypt, "audience", "issuer", &claims)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	signer, err := encrypt.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	issuer := &jwtkit.Issuer{Signer: signer, Issuer: "issuer", Expiration: 60000, TimeUnit: jwtkit.Seconds}
+	standardToken, err := issuer.Issue("subject", claims)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	clock := jwtkit.NewFrozenClock(time.Now())
+	seen := map[jwtkit.TokenFormat]int{}
+	mv := &jwtkit.MigrationVerifier{
+		Standard:    &jwtkit.JWTVerifier{Resolver: encrypt, Validator: &jwtkit.Validator{TimeUnit: jwtkit.Seconds}},
+		Legacy:      &jwtkit.LegacyJWTVerifier{ECDSA: encrypt},
+		LegacyUntil: clock.Now().Add(time.Hour),
+		Clock:       clock,
+		OnVerified:  func(format jwtkit.TokenFormat) { seen[format]++ },
+	}
+
+	testCases := []struct {
+		name     string
+		token    jwtkit.JWTString
+		format   jwtkit.TokenFormat
+		expected error
+	}{
+		{"standard", standardToken, jwtkit.FormatStandard, nil},
+		{"legacy within window", legacyToken, jwtkit.FormatLegacy, nil},
+	}
+
+	for _, tc := range testCases {
+		_, format, err := mv.VerifyTokenFormat(tc.token)
+		if !errors.Is(err, tc.expected) || format != tc.format {
+			t.Fatalf("%s expected %s %v got: %s %v", tc.name, tc.format, tc.expected, format, err)
+		}
+	}
+	if seen[jwtkit.FormatStandard] != 1 || seen[jwtkit.FormatLegacy] != 1 {
+		t.Fatalf("expected one token of each format got: %v", seen)
+	}
+
+	clock.Advance(time.Hour)
+	_, err = mv.VerifyToken(legacyToken)
+	if !errors.Is(err, jwtkit.ErrLegacyTokenRejected) {
+		t.Fatalf("expected legacy token rejected after the window got: %v", err)
+	}
+
+	reissued, err := jwtkit.ReissueLegacy(&jwtkit.LegacyJWTVerifier{ECDSA: encrypt}, issuer, legacyToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	legacyJWT, err := jwtkit.GetJWT(legacyToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	reissuedJWT, format, err := mv.VerifyTokenFormat(reissued)
+	if err != nil || format != jwtkit.FormatStandard {
+		t.Fatalf("expected reissued standard token to verify got: %s %v", format, err)
+	}
+	if reissuedJWT.Payload.Id != legacyJWT.Payload.Id || reissuedJWT.Payload.Claims["role"] != "admin" ||
+		reissuedJWT.Payload.ExpiredAt != legacyJWT.Payload.ExpiredAt/1000 {
+		t.Fatalf("expected same claims got: %+v from: %+v", reissuedJWT.Payload, legacyJWT.Payload)
+	}
+}
@@ -0,0 +1,202 @@
+package jwtkit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxTokenSize bounds the work done on a token before its signature is checked.
+const MaxTokenSize = 16 * 1024
+
+const (
+	SegmentToken     = "token"
+	SegmentHeader    = "header"
+	SegmentPayload   = "payload"
+	SegmentSignature = "signature"
+)
+
+var (
+	ErrMalformed   = errors.New("malformed token")
+	ErrBadEncoding = errors.New("token has bad encoding")
+)
+
+// ParseError tells which segment of a token failed to parse, Err is ErrMalformed, ErrBadEncoding
+// or ErrUnsupportedAlg so callers can match it with errors.Is.
+type ParseError struct {
+	Segment string
+	Err     error
+	Detail  string
+}
+
+func (pe *ParseError) Error() string {
+	if pe.Detail == "" {
+		return fmt.Sprintf("%s: %s", pe.Segment, pe.Err.Error())
+	}
+	return fmt.Sprintf("%s: %s: %s", pe.Segment, pe.Err.Error(), pe.Detail)
+}
+
+func (pe *ParseError) Unwrap() error { return pe.Err }
+
+var (
+	rawEncoding    = base64.RawURLEncoding.Strict()
+	paddedEncoding = base64.URLEncoding.Strict()
+)
+
+type parsedToken struct {
+	format    TokenFormat
+	segments  []string
+	header    *Header
+	payload   []byte
+	signature []byte
+}
+
+func (pt *parsedToken) signingInput() []byte {
+	return []byte(pt.segments[0] + "." + pt.segments[1])
+}
+
+// parseToken is the only place tokens are split and decoded. Standard tokens must be unpadded
+// base64url, legacy tokens padded base64url, and every JSON segment must be a single object
+// without duplicate keys, top level keys are compared case insensitively as encoding/json
+// matches struct fields that way.
+func parseToken(j JWTString) (*parsedToken, error) {
+	if len(j) > MaxTokenSize {
+		return nil, &ParseError{SegmentToken, ErrMalformed, fmt.Sprintf("longer than %d bytes", MaxTokenSize)}
+	}
+	segments := strings.Split(string(j), ".")
+	if len(segments) != 3 {
+		return nil, &ParseError{SegmentToken, ErrMalformed, fmt.Sprintf("%d segments, expected 3", len(segments))}
+	}
+	if segments[0] == "" {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, "empty"}
+	}
+
+	padded := strings.HasSuffix(segments[0], "=")
+	encoding := rawEncoding
+	if padded {
+		encoding = paddedEncoding
+	}
+	headerJSON, err := encoding.DecodeString(segments[0])
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrBadEncoding, err.Error()}
+	}
+	err = checkJSONObject(headerJSON)
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, err.Error()}
+	}
+	var header Header
+	err = json.Unmarshal(headerJSON, &header)
+	if err != nil {
+		return nil, &ParseError{SegmentHeader, ErrMalformed, err.Error()}
+	}
+	if !isKnownAlgorithm(header.Algorithm) {
+		return nil, &ParseError{SegmentHeader, ErrUnsupportedAlg, fmt.Sprintf("%q", header.Algorithm)}
+	}
+
+	// alg is checked first so unsecured "none" tokens, which have an empty signature, report ErrUnsupportedAlg
+	for i, segment := range []string{SegmentPayload, SegmentSignature} {
+		if segments[i+1] == "" {
+			return nil, &ParseError{segment, ErrMalformed, "empty"}
+		}
+	}
+
+	pt := &parsedToken{format: header.format(), segments: segments, header: &header}
+	if pt.format == FormatLegacy {
+		encoding = paddedEncoding
+	} else if padded {
+		return nil, &ParseError{SegmentHeader, ErrBadEncoding, "padding is not allowed"}
+	}
+
+	pt.payload, err = encoding.DecodeString(segments[1])
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrBadEncoding, err.Error()}
+	}
+	err = checkJSONObject(pt.payload)
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+
+	pt.signature, err = encoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, &ParseError{SegmentSignature, ErrBadEncoding, err.Error()}
+	}
+	if pt.format == FormatLegacy {
+		err = checkJSONObject(pt.signature)
+		if err != nil {
+			return nil, &ParseError{SegmentSignature, ErrMalformed, err.Error()}
+		}
+	}
+
+	return pt, nil
+}
+
+func isKnownAlgorithm(alg string) bool {
+	if _, ok := algorithmHash(alg); ok {
+		return true
+	}
+	return alg == AlgorithmEdDSA || alg == AlgorithmLegacyECDSA
+}
+
+// checkJSONObject requires data to be exactly one JSON object without duplicate keys.
+func checkJSONObject(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return errors.New("not a JSON object")
+	}
+	err = checkJSONValue(decoder, '{', true)
+	if err != nil {
+		return err
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return errors.New("data after the JSON object")
+	}
+
+	return nil
+}
+
+func checkJSONValue(decoder *json.Decoder, delim json.Delim, topLevel bool) error {
+	keys := make(map[string]bool)
+	for decoder.More() {
+		if delim == '{' {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, ok := token.(string)
+			if !ok {
+				return errors.New("object key is not a string")
+			}
+			if topLevel {
+				key = strings.ToLower(key)
+			}
+			if keys[key] {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			keys[key] = true
+		}
+
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if nested, ok := token.(json.Delim); ok {
+			err = checkJSONValue(decoder, nested, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := decoder.Token()
+	return err
+}
@@ -0,0 +1,96 @@
+package jwtkit_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func segment(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestParseErrors(t *testing.T) {
+	header := segment(`{"alg":"HS256","typ":"JWT"}`)
+	payload := segment(`{"sub":"subject"}`)
+
+	testCases := []struct {
+		name     string
+		token    jwtkit.JWTString
+		expected error
+		segment  string
+	}{
+		{"one segment", "abc", jwtkit.ErrMalformed, jwtkit.SegmentToken},
+		{"two segments", jwtkit.JWTString(header + "." + payload), jwtkit.ErrMalformed, jwtkit.SegmentToken},
+		{"four segments", jwtkit.JWTString(header + "." + payload + ".c2ln.c2ln"), jwtkit.ErrMalformed, jwtkit.SegmentToken},
+		{"too large", jwtkit.JWTString(header + "." + strings.Repeat("a", jwtkit.MaxTokenSize) + ".c2ln"), jwtkit.ErrMalformed, jwtkit.SegmentToken},
+		{"empty signature", jwtkit.JWTString(header + "." + payload + "."), jwtkit.ErrMalformed, jwtkit.SegmentSignature},
+		{"bad header encoding", jwtkit.JWTString("a*b." + payload + ".c2ln"), jwtkit.ErrBadEncoding, jwtkit.SegmentHeader},
+		{"padded standard token", jwtkit.JWTString(base64.URLEncoding.EncodeToString([]byte(`{"alg": "HS256"}`)) + "." + payload + ".c2ln"), jwtkit.ErrBadEncoding, jwtkit.SegmentHeader},
+		{"header not an object", jwtkit.JWTString(segment(`["HS256"]`) + "." + payload + ".c2ln"), jwtkit.ErrMalformed, jwtkit.SegmentHeader},
+		{"duplicate alg", jwtkit.JWTString(segment(`{"alg":"HS256","alg":"none"}`) + "." + payload + ".c2ln"), jwtkit.ErrMalformed, jwtkit.SegmentHeader},
+		{"unknown alg", jwtkit.JWTString(segment(`{"alg":"none"}`) + "." + payload + "."), jwtkit.ErrUnsupportedAlg, jwtkit.SegmentHeader},
+		{"duplicate exp", jwtkit.JWTString(header + "." + segment(`{"exp":1,"EXP":9999999999999}`) + ".c2ln"), jwtkit.ErrMalformed, jwtkit.SegmentPayload},
+		{"trailing data", jwtkit.JWTString(header + "." + segment(`{"sub":"a"}{}`) + ".c2ln"), jwtkit.ErrMalformed, jwtkit.SegmentPayload},
+		{"bad signature encoding", jwtkit.JWTString(header + "." + payload + ".c2ln="), jwtkit.ErrBadEncoding, jwtkit.SegmentSignature},
+	}
+
+	for _, tc := range testCases {
+		_, err := jwtkit.GetJWT(tc.token)
+		var parseErr *jwtkit.ParseError
+		if !errors.Is(err, tc.expected) || !errors.As(err, &parseErr) || parseErr.Segment != tc.segment {
+			t.Fatalf("%s expected %s %v got: %v", tc.name, tc.segment, tc.expected, err)
+		}
+	}
+}
+
+// FuzzVerify runs arbitrary tokens through every entry point, none of them may panic and no
+// token that was not signed by the test keys may verify.
+func FuzzVerify(f *testing.F) {
+	encrypt := newTestECDSA(f)
+	claims := map[string]interface{}{"role": "admin"}
+	legacyToken, err := jwtkit.JWTExpiration(60000).GenerateSignedJWTString(encrypt, "audience", "issuer", &claims)
+	if err != nil {
+		f.Fatalf("error: %v", err)
+	}
+	standardToken, err := jwtkit.JWTExpiration(60000).GenerateStandardJWTString(encrypt, "audience", "issuer", &claims)
+	if err != nil {
+		f.Fatalf("error: %v", err)
+	}
+
+	for _, seed := range []string{string(legacyToken), string(standardToken), "abc", "a.b", "a.b.c", "..", "e30.e30.", segment(`{"alg":"ES256"}`) + ".e30.AAAA"} {
+		f.Add(seed)
+	}
+
+	verifier := &jwtkit.MigrationVerifier{
+		Standard: &jwtkit.JWTVerifier{Resolver: encrypt, Validator: &jwtkit.Validator{TimeUnit: jwtkit.Seconds}},
+		Legacy:   &jwtkit.LegacyJWTVerifier{ECDSA: encrypt, Validator: &jwtkit.Validator{TimeUnit: jwtkit.Milliseconds}},
+	}
+	for _, seed := range []jwtkit.JWTString{legacyToken, standardToken} {
+		if _, err := verifier.VerifyToken(seed); err != nil {
+			f.Fatalf("expected the seed to verify got: %v", err)
+		}
+	}
+	f.Fuzz(func(t *testing.T, token string) {
+		j := jwtkit.JWTString(token)
+		jwtkit.GetJWT(j)
+		jwtkit.DetectFormat(j)
+		jwtkit.ValidateExpired(j)
+		jwtkit.VerifyJWTStringWith(encrypt, j)
+		ok, err := jwtkit.VerifyJWTString(encrypt, j)
+		if ok != (err == nil) {
+			t.Fatalf("VerifyJWTString returned %t with %v", ok, err)
+		}
+		_, err = verifier.VerifyToken(j)
+		if err == nil && signingInput(j) != signingInput(legacyToken) && signingInput(j) != signingInput(standardToken) {
+			t.Fatalf("forged token verified: %s", token)
+		}
+	})
+}
+
+func signingInput(j jwtkit.JWTString) string {
+	return string(j[:strings.LastIndexByte(string(j), '.')])
+}
@@ -0,0 +1,361 @@
+package jwtkit
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+// PASETO purposes, a verifier is configured with exactly one so the token can never pick its own algorithm.
+const (
+	PasetoV4Public = "v4.public"
+	PasetoV4Local  = "v4.local"
+	TypePaseto     = "PASETO"
+)
+
+const (
+	pasetoLocalKeySize = 32
+	pasetoNonceSize    = 32
+	pasetoMACSize      = 32
+)
+
+var ErrPasetoFooterMismatch = errors.New("paseto footer mismatch")
+
+// PasetoIssuer issues PASETO v4 tokens with the same claims as Issuer. The registered time claims
+// are written as RFC 3339 strings as PASETO requires, TimeUnit only matters for the Payload the
+// PasetoVerifier returns.
+type PasetoIssuer struct {
+	// Purpose is PasetoV4Public, signed with an Ed25519 private key, or PasetoV4Local,
+	// encrypted with a 32 byte []byte secret
+	Purpose           string
+	Keys              KeyProvider
+	Issuer            string
+	Audience          Audience
+	Expiration        JWTExpiration
+	Clock             Clock
+	TimeUnit          TimeUnit
+	Footer            []byte
+	ImplicitAssertion []byte
+}
+
+func (pi *PasetoIssuer) Issue(subject string, claims map[string]interface{}) (JWTString, error) {
+	rc := &RegisteredClaims{Subject: subject}
+	err := (&Issuer{Issuer: pi.Issuer, Audience: pi.Audience, Expiration: pi.Expiration, Clock: pi.Clock, TimeUnit: pi.TimeUnit}).fill(rc)
+	if err != nil {
+		return "", err
+	}
+
+	payload := &Payload{
+		Id:        rc.Id,
+		Audiences: rc.Audience,
+		Issuer:    rc.Issuer,
+		Subject:   rc.Subject,
+		IssuedAt:  rc.IssuedAt,
+		ExpiredAt: rc.ExpiredAt,
+		NotBefore: rc.NotBefore,
+		Claims:    claims,
+	}
+	message, err := marshalPasetoClaims(payload, pi.TimeUnit)
+	if err != nil {
+		return "", err
+	}
+
+	privateKey, err := pi.Keys.PrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	var body []byte
+	switch pi.Purpose {
+	case PasetoV4Public:
+		body, err = pasetoSign(privateKey, message, pi.Footer, pi.ImplicitAssertion)
+	case PasetoV4Local:
+		body, err = pasetoEncrypt(privateKey, message, pi.Footer, pi.ImplicitAssertion)
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnsupportedAlg, pi.Purpose)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token := pi.Purpose + "." + rawEncoding.EncodeToString(body)
+	if len(pi.Footer) != 0 {
+		token += "." + rawEncoding.EncodeToString(pi.Footer)
+	}
+
+	return JWTString(token), nil
+}
+
+// PasetoVerifier is the TokenVerifier for PASETO v4, the returned JWT has the purpose as its alg
+// header and no signature.
+type PasetoVerifier struct {
+	Purpose   string
+	Keys      KeyProvider
+	Validator *Validator
+	// Footer must equal the token footer exactly, nil accepts only tokens without a footer
+	Footer            []byte
+	ImplicitAssertion []byte
+}
+
+func (pv *PasetoVerifier) VerifyToken(j JWTString) (*JWT, error) {
+	if len(j) > MaxTokenSize {
+		return nil, &ParseError{SegmentToken, ErrMalformed, fmt.Sprintf("longer than %d bytes", MaxTokenSize)}
+	}
+	if pv.Purpose != PasetoV4Public && pv.Purpose != PasetoV4Local {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, pv.Purpose)
+	}
+	if !strings.HasPrefix(string(j), pv.Purpose+".") {
+		return nil, &ParseError{SegmentHeader, ErrUnsupportedAlg, fmt.Sprintf("expected %s token", pv.Purpose)}
+	}
+
+	segments := strings.Split(string(j[len(pv.Purpose)+1:]), ".")
+	if len(segments) > 2 || segments[0] == "" {
+		return nil, &ParseError{SegmentToken, ErrMalformed, "expected a payload and an optional footer"}
+	}
+	body, err := rawEncoding.DecodeString(segments[0])
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrBadEncoding, err.Error()}
+	}
+	var footer []byte
+	if len(segments) == 2 {
+		footer, err = rawEncoding.DecodeString(segments[1])
+		if err != nil || len(footer) == 0 {
+			return nil, &ParseError{"footer", ErrBadEncoding, "footer must be non empty base64url"}
+		}
+	}
+	if subtle.ConstantTimeCompare(footer, pv.Footer) != 1 {
+		return nil, ErrPasetoFooterMismatch
+	}
+
+	var message []byte
+	if pv.Purpose == PasetoV4Public {
+		publicKey, err := pv.Keys.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		message, err = pasetoVerify(publicKey, body, footer, pv.ImplicitAssertion)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		privateKey, err := pv.Keys.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		message, err = pasetoDecrypt(privateKey, body, footer, pv.ImplicitAssertion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := unmarshalPasetoClaims(message, pv.timeUnit())
+	if err != nil {
+		return nil, err
+	}
+	validator := pv.Validator
+	if validator == nil {
+		validator = &Validator{}
+	}
+	err = validator.Validate(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWT{Header: &Header{Algorithm: pv.Purpose, Type: TypePaseto}, Payload: payload}, nil
+}
+
+func (pv *PasetoVerifier) timeUnit() TimeUnit {
+	if pv.Validator == nil {
+		return Seconds
+	}
+	return pv.Validator.TimeUnit
+}
+
+func pasetoSign(privateKey crypto.PrivateKey, message []byte, footer []byte, implicit []byte) ([]byte, error) {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s requires an Ed25519 private key got %T", ErrAlgorithmKeyMismatch, PasetoV4Public, privateKey)
+	}
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return nil, fmt.Errorf("%w: %s requires an Ed25519 private key got %T", ErrAlgorithmKeyMismatch, PasetoV4Public, signer.Public())
+	}
+
+	signature, err := signer.Sign(rand.Reader, pae([]byte(PasetoV4Public+"."), message, footer, implicit), crypto.Hash(0))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, message...), signature...), nil
+}
+
+func pasetoVerify(publicKey crypto.PublicKey, body []byte, footer []byte, implicit []byte) ([]byte, error) {
+	edPublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s requires ed25519.PublicKey got %T", ErrAlgorithmKeyMismatch, PasetoV4Public, publicKey)
+	}
+	if len(body) < ed25519.SignatureSize {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, "shorter than a signature"}
+	}
+
+	message, signature := body[:len(body)-ed25519.SignatureSize], body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(edPublicKey, pae([]byte(PasetoV4Public+"."), message, footer, implicit), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return message, nil
+}
+
+func pasetoEncrypt(privateKey crypto.PrivateKey, message []byte, footer []byte, implicit []byte) ([]byte, error) {
+	key, err := pasetoLocalKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, pasetoNonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionKey, counterNonce, authKey := pasetoSplitKey(key, nonce)
+	stream, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, len(message))
+	stream.XORKeyStream(ciphertext, message)
+
+	mac := pasetoMAC(authKey, nonce, ciphertext, footer, implicit)
+
+	return append(append(nonce, ciphertext...), mac...), nil
+}
+
+func pasetoDecrypt(privateKey crypto.PrivateKey, body []byte, footer []byte, implicit []byte) ([]byte, error) {
+	key, err := pasetoLocalKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < pasetoNonceSize+pasetoMACSize {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, "shorter than nonce and tag"}
+	}
+
+	nonce, ciphertext, mac := body[:pasetoNonceSize], body[pasetoNonceSize:len(body)-pasetoMACSize], body[len(body)-pasetoMACSize:]
+	encryptionKey, counterNonce, authKey := pasetoSplitKey(key, nonce)
+	if subtle.ConstantTimeCompare(mac, pasetoMAC(authKey, nonce, ciphertext, footer, implicit)) != 1 {
+		return nil, ErrDecryption
+	}
+
+	stream, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	stream.XORKeyStream(message, ciphertext)
+
+	return message, nil
+}
+
+func pasetoLocalKey(privateKey crypto.PrivateKey) ([]byte, error) {
+	key, ok := privateKey.([]byte)
+	if !ok || len(key) != pasetoLocalKeySize {
+		return nil, fmt.Errorf("%w: %s requires a %d byte []byte key", ErrAlgorithmKeyMismatch, PasetoV4Local, pasetoLocalKeySize)
+	}
+	return key, nil
+}
+
+// pasetoSplitKey derives the XChaCha20 key and nonce and the BLAKE2b-MAC key from the nonce.
+func pasetoSplitKey(key []byte, nonce []byte) ([]byte, []byte, []byte) {
+	encryptionHash, _ := blake2b.New(32+chacha20.NonceSizeX, key)
+	encryptionHash.Write([]byte("paseto-encryption-key"))
+	encryptionHash.Write(nonce)
+	derived := encryptionHash.Sum(nil)
+
+	authHash, _ := blake2b.New(32, key)
+	authHash.Write([]byte("paseto-auth-key-for-aead"))
+	authHash.Write(nonce)
+
+	return derived[:32], derived[32:], authHash.Sum(nil)
+}
+
+func pasetoMAC(authKey []byte, nonce []byte, ciphertext []byte, footer []byte, implicit []byte) []byte {
+	mac, _ := blake2b.New(pasetoMACSize, authKey)
+	mac.Write(pae([]byte(PasetoV4Local+"."), nonce, ciphertext, footer, implicit))
+	return mac.Sum(nil)
+}
+
+// pae is the PASETO pre-authentication encoding, every piece is prefixed with its length.
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	lengthBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(lengthBuf, uint64(len(pieces)))
+	buf.Write(lengthBuf)
+	for _, piece := range pieces {
+		binary.LittleEndian.PutUint64(lengthBuf, uint64(len(piece))&(1<<63-1))
+		buf.Write(lengthBuf)
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
+
+var pasetoTimeClaims = []string{"iat", "exp", "nbf"}
+
+func marshalPasetoClaims(p *Payload, unit TimeUnit) ([]byte, error) {
+	flat := p.FlatClaims()
+	for _, name := range pasetoTimeClaims {
+		if timestamp, ok := flat[name].(int64); ok {
+			flat[name] = unit.Time(timestamp).UTC().Format(time.RFC3339)
+		}
+	}
+	return json.Marshal(flat)
+}
+
+func unmarshalPasetoClaims(message []byte, unit TimeUnit) (*Payload, error) {
+	err := checkJSONObject(message)
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+
+	var flat map[string]json.RawMessage
+	err = json.Unmarshal(message, &flat)
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+	for _, name := range pasetoTimeClaims {
+		raw, ok := flat[name]
+		if !ok {
+			continue
+		}
+		var formatted string
+		err = json.Unmarshal(raw, &formatted)
+		if err != nil {
+			return nil, &ParseError{SegmentPayload, ErrMalformed, name + " must be an RFC 3339 string"}
+		}
+		parsed, err := time.Parse(time.RFC3339, formatted)
+		if err != nil {
+			return nil, &ParseError{SegmentPayload, ErrMalformed, name + ": " + err.Error()}
+		}
+		flat[name], _ = json.Marshal(unit.Timestamp(parsed))
+	}
+
+	converted, err := json.Marshal(flat)
+	if err != nil {
+		return nil, err
+	}
+	var payload Payload
+	err = payload.unmarshalFlat(converted)
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+
+	return &payload, nil
+}
@@ -0,0 +1,148 @@
+package jwtkit_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestPaseto(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	publicKeys, err := jwtkit.NewStaticKeyProvider(edKey, nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	localKeys, err := jwtkit.NewStaticKeyProvider([]byte("0123456789abcdef0123456789abcdef"), nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	clock := jwtkit.NewFrozenClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	for _, tc := range []struct {
+		purpose string
+		keys    jwtkit.KeyProvider
+	}{
+		{jwtkit.PasetoV4Public, publicKeys},
+		{jwtkit.PasetoV4Local, localKeys},
+	} {
+		var issuer jwtkit.TokenIssuer = &jwtkit.PasetoIssuer{
+			Purpose:    tc.purpose,
+			Keys:       tc.keys,
+			Issuer:     "issuer",
+			Expiration: 60000,
+			Clock:      clock,
+			TimeUnit:   jwtkit.Seconds,
+			Footer:     []byte(`{"kid":"k1"}`),
+		}
+		j, err := issuer.Issue("subject", map[string]interface{}{"tenant": "acme"})
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.purpose, err)
+		}
+		if !strings.HasPrefix(string(j), tc.purpose+".") {
+			t.Fatalf("%s unexpected token: %s", tc.purpose, j)
+		}
+
+		verifier := &jwtkit.PasetoVerifier{
+			Purpose:   tc.purpose,
+			Keys:      tc.keys,
+			Validator: &jwtkit.Validator{Clock: clock, TimeUnit: jwtkit.Seconds, Issuer: "issuer"},
+			Footer:    []byte(`{"kid":"k1"}`),
+		}
+		jwt, err := verifier.VerifyToken(j)
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.purpose, err)
+		}
+		if jwt.Payload.Subject != "subject" || jwt.Payload.Claims["tenant"] != "acme" || jwt.Payload.ExpiredAt != clock.Now().Unix()+60 {
+			t.Fatalf("%s unexpected payload: %+v", tc.purpose, jwt.Payload)
+		}
+
+		otherPurpose, otherKeys := jwtkit.PasetoV4Local, localKeys
+		if tc.purpose == jwtkit.PasetoV4Local {
+			otherPurpose, otherKeys = jwtkit.PasetoV4Public, publicKeys
+		}
+		testCases := []struct {
+			name     string
+			token    jwtkit.JWTString
+			modify   func(pv *jwtkit.PasetoVerifier)
+			expected error
+		}{
+			{"other purpose", j, func(pv *jwtkit.PasetoVerifier) { pv.Purpose, pv.Keys = otherPurpose, otherKeys }, jwtkit.ErrUnsupportedAlg},
+			{"footer mismatch", j, func(pv *jwtkit.PasetoVerifier) { pv.Footer = []byte(`{"kid":"k2"}`) }, jwtkit.ErrPasetoFooterMismatch},
+			{"footer stripped", j[:strings.LastIndexByte(string(j), '.')], func(pv *jwtkit.PasetoVerifier) {}, jwtkit.ErrPasetoFooterMismatch},
+			{"expired", j, func(pv *jwtkit.PasetoVerifier) { clock.Advance(2 * time.Minute) }, jwtkit.ErrExpired},
+		}
+		for _, c := range testCases {
+			pv := *verifier
+			c.modify(&pv)
+			_, err := pv.VerifyToken(c.token)
+			if !errors.Is(err, c.expected) {
+				t.Fatalf("%s %s expected %v got: %v", tc.purpose, c.name, c.expected, err)
+			}
+		}
+		clock.Set(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+}
+
+func TestPasetoVectors(t *testing.T) {
+	// official vectors of github.com/paseto-standard/test-vectors v4.json
+	secretKey, _ := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	publicKey, _ := hex.DecodeString("1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	localKey, _ := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	publicKeys, err := jwtkit.NewStaticKeyProvider(nil, ed25519.PublicKey(publicKey))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if !ed25519.PrivateKey(secretKey).Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(publicKey)) {
+		t.Fatalf("expected the vector secret key to hold the vector public key")
+	}
+	localKeys, err := jwtkit.NewStaticKeyProvider(localKey, nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	footer := []byte(`{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`)
+
+	testCases := []struct {
+		name     string
+		purpose  string
+		keys     jwtkit.KeyProvider
+		token    jwtkit.JWTString
+		data     string
+		footer   []byte
+		implicit []byte
+	}{
+		{"4-E-3", jwtkit.PasetoV4Local, localKeys, "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6-tyebyWG6Ov7kKvBdkrrAJ837lKP3iDag2hzUPHuMKA", "this is a secret message", nil, nil},
+		{"4-E-5", jwtkit.PasetoV4Local, localKeys, "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4x-RMNXtQNbz7FvFZ_G-lFpk5RG3EOrwDL6CgDqcerSQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9", "this is a secret message", footer, nil},
+		{"4-S-1", jwtkit.PasetoV4Public, publicKeys, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA", "this is a signed message", nil, nil},
+		{"4-S-2", jwtkit.PasetoV4Public, publicKeys, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9", "this is a signed message", footer, nil},
+		{"4-S-3", jwtkit.PasetoV4Public, publicKeys, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9NPWciuD3d0o5eXJXG5pJy-DiVEoyPYWs1YSTwWHNJq6DZD3je5gf-0M4JR9ipdUSJbIovzmBECeaWmaqcaP0DQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9", "this is a signed message", footer, []byte(`{"test-vector":"4-S-3"}`)},
+	}
+
+	// the vectors expire at 2022-01-01T00:00:00+00:00
+	expiredAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	validator := &jwtkit.Validator{Clock: jwtkit.NewFrozenClock(expiredAt.Add(-time.Hour)), TimeUnit: jwtkit.Seconds}
+	for _, tc := range testCases {
+		verifier := &jwtkit.PasetoVerifier{Purpose: tc.purpose, Keys: tc.keys, Validator: validator, Footer: tc.footer, ImplicitAssertion: tc.implicit}
+		jwt, err := verifier.VerifyToken(tc.token)
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		if jwt.Payload.Claims["data"] != tc.data || jwt.Payload.ExpiredAt != expiredAt.Unix() {
+			t.Fatalf("%s unexpected payload: %+v", tc.name, jwt.Payload)
+		}
+
+		// the implicit assertion is authenticated too, a different one must not verify
+		verifier.ImplicitAssertion = []byte(`{"test-vector":"other"}`)
+		if _, err = verifier.VerifyToken(tc.token); err == nil {
+			t.Fatalf("%s expected a different implicit assertion to fail", tc.name)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package jwtkit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	refreshTokenUse = "refresh"
+	// TypeRefreshJWT is the typ header of refresh tokens, so they are never mistaken for access tokens
+	TypeRefreshJWT = "refresh+jwt"
+)
+
+var (
+	ErrRefreshTokenUnknown  = errors.New("unknown refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token reused, token family revoked")
+	ErrRefreshFamilyRevoked = errors.New("refresh token family is revoked")
+	ErrNotRefreshToken      = errors.New("token is not a refresh token")
+	ErrRefreshTokenRefused  = errors.New("refresh token is not accepted as access token")
+)
+
+type RefreshToken struct {
+	Id        string
+	Family    string
+	Subject   string
+	ExpiredAt int64
+	Used      bool
+	// Claims are the custom claims of the access token, they are carried over on every rotation
+	Claims map[string]interface{}
+}
+
+type RefreshStore interface {
+	Save(token *RefreshToken) error
+	// Get returns ErrRefreshTokenUnknown when the token does not exist
+	Get(id string) (*RefreshToken, error)
+	// MarkUsed must atomically report whether the token was already used before this call
+	MarkUsed(id string) (alreadyUsed bool, err error)
+	RevokeFamily(family string) error
+	IsFamilyRevoked(family string) (bool, error)
+}
+
+type TokenPair struct {
+	AccessToken  JWTString
+	RefreshToken JWTString
+	ExpiredAt    int64
+}
+
+// RefreshIssuer issues access/refresh pairs, every refresh rotates the refresh token and a reused
+// refresh token revokes its whole family.
+type RefreshIssuer struct {
+	Access            *Issuer
+	RefreshExpiration JWTExpiration
+	Resolver          VerifierResolver
+	Store             RefreshStore
+}
+
+type refreshClaims struct {
+	RegisteredClaims
+	Family   string `json:"fam"`
+	TokenUse string `json:"token_use"`
+	// ClientID is copied from the access token claims, so revocation can tell whose token it is
+	ClientID string `json:"client_id,omitempty"`
+}
+
+func (ri *RefreshIssuer) IssuePair(subject string, claims map[string]interface{}) (*TokenPair, error) {
+	family, err := newJTI()
+	if err != nil {
+		return nil, err
+	}
+	return ri.issuePair(family, subject, claims)
+}
+
+func (ri *RefreshIssuer) Refresh(refreshToken JWTString) (*TokenPair, error) {
+	parsed, err := ParseAndVerify[refreshClaims](refreshToken, ri.Resolver, ri.validator())
+	if err != nil {
+		return nil, err
+	}
+	if parsed.TokenUse != refreshTokenUse || parsed.Family == "" {
+		return nil, ErrNotRefreshToken
+	}
+
+	revoked, err := ri.Store.IsFamilyRevoked(parsed.Family)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrRefreshFamilyRevoked
+	}
+
+	stored, err := ri.Store.Get(parsed.Id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Family != parsed.Family || stored.Subject != parsed.Subject {
+		return nil, ErrRefreshTokenUnknown
+	}
+
+	alreadyUsed, err := ri.Store.MarkUsed(stored.Id)
+	if err != nil {
+		return nil, err
+	}
+	if alreadyUsed {
+		err = ri.Store.RevokeFamily(stored.Family)
+		if err != nil {
+			return nil, fmt.Errorf("%w, error revoke family: %s", ErrRefreshTokenReused, err.Error())
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return ri.issuePair(stored.Family, stored.Subject, stored.Claims)
+}
+
+func (ri *RefreshIssuer) RevokeFamily(refreshToken JWTString) error {
+	parsed, err := ParseAndVerify[refreshClaims](refreshToken, ri.Resolver, ri.validator())
+	if err != nil {
+		return err
+	}
+	if parsed.TokenUse != refreshTokenUse || parsed.Family == "" {
+		return ErrNotRefreshToken
+	}
+	return ri.Store.RevokeFamily(parsed.Family)
+}
+
+// IsRefreshToken reports whether p is the payload of a refresh token issued by a RefreshIssuer.
+func (p *Payload) IsRefreshToken() bool {
+	tokenUse, _ := p.Claims["token_use"].(string)
+	_, hasFamily := p.Claims["fam"]
+	return tokenUse == refreshTokenUse && hasFamily
+}
+
+func (ri *RefreshIssuer) validator() *Validator {
+	return &Validator{Clock: ri.Access.Clock, TimeUnit: ri.Access.TimeUnit, Issuer: ri.Access.Issuer, AllowRefreshTokens: true}
+}
+
+func (ri *RefreshIssuer) issuePair(family string, subject string, claims map[string]interface{}) (*TokenPair, error) {
+	accessToken, err := ri.Access.Issue(subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshIssuer := &Issuer{
+		Signer:     ri.Access.Signer,
+		Issuer:     ri.Access.Issuer,
+		Audience:   ri.Access.Audience,
+		Expiration: ri.RefreshExpiration,
+		Clock:      ri.Access.Clock,
+		TimeUnit:   ri.Access.TimeUnit,
+	}
+	refresh := &refreshClaims{Family: family, TokenUse: refreshTokenUse}
+	refresh.Subject = subject
+	refresh.ClientID, _ = claims["client_id"].(string)
+	refreshToken, err := signClaims(refreshIssuer, refresh, TypeRefreshJWT)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ri.Store.Save(&RefreshToken{
+		Id:        refresh.Id,
+		Family:    family,
+		Subject:   subject,
+		ExpiredAt: refresh.ExpiredAt,
+		Claims:    claims,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiredAt:    refresh.ExpiredAt,
+	}, nil
+}
+
+// MemoryRefreshStore evicts expired tokens using Clock and TimeUnit, set them like the Issuer's.
+type MemoryRefreshStore struct {
+	Clock           Clock
+	TimeUnit        TimeUnit
+	mu              sync.Mutex
+	tokens          map[string]*RefreshToken
+	revokedFamilies map[string]int64
+}
+
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		tokens:          make(map[string]*RefreshToken),
+		revokedFamilies: make(map[string]int64),
+	}
+}
+
+func (mrs *MemoryRefreshStore) Save(token *RefreshToken) error {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	mrs.evictExpired(mrs.TimeUnit.now(mrs.Clock))
+	stored := *token
+	mrs.tokens[token.Id] = &stored
+
+	return nil
+}
+
+func (mrs *MemoryRefreshStore) Get(id string) (*RefreshToken, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	token, ok := mrs.tokens[id]
+	if !ok {
+		return nil, ErrRefreshTokenUnknown
+	}
+	result := *token
+
+	return &result, nil
+}
+
+func (mrs *MemoryRefreshStore) MarkUsed(id string) (bool, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	token, ok := mrs.tokens[id]
+	if !ok {
+		return false, ErrRefreshTokenUnknown
+	}
+	alreadyUsed := token.Used
+	token.Used = true
+
+	return alreadyUsed, nil
+}
+
+// RevokeFamily drops every token of the family and remembers the revocation until the last one expires.
+func (mrs *MemoryRefreshStore) RevokeFamily(family string) error {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	expiredAt := mrs.revokedFamilies[family]
+	for id, token := range mrs.tokens {
+		if token.Family != family {
+			continue
+		}
+		if token.ExpiredAt > expiredAt {
+			expiredAt = token.ExpiredAt
+		}
+		delete(mrs.tokens, id)
+	}
+	mrs.revokedFamilies[family] = expiredAt
+
+	return nil
+}
+
+func (mrs *MemoryRefreshStore) IsFamilyRevoked(family string) (bool, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	_, revoked := mrs.revokedFamilies[family]
+	return revoked, nil
+}
+
+func (mrs *MemoryRefreshStore) evictExpired(now int64) {
+	for id, token := range mrs.tokens {
+		if token.ExpiredAt != 0 && token.ExpiredAt <= now {
+			delete(mrs.tokens, id)
+		}
+	}
+	for family, expiredAt := range mrs.revokedFamilies {
+		if expiredAt <= now {
+			delete(mrs.revokedFamilies, family)
+		}
+	}
+}
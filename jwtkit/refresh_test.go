@@ -0,0 +1,81 @@
+package jwtkit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestRefreshIssuerRotation(t *testing.T) {
+	ks := jwtkit.NewKeySet()
+	ks.Add("hmac", []byte("a secret that is long enough for HS256"))
+	ks.Activate("hmac")
+	signer, err := ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	refreshIssuer := &jwtkit.RefreshIssuer{
+		Access:            &jwtkit.Issuer{Signer: signer, Issuer: "issuer", Expiration: 60000},
+		RefreshExpiration: 3600000,
+		Resolver:          ks,
+		Store:             jwtkit.NewMemoryRefreshStore(),
+	}
+
+	first, err := refreshIssuer.IssuePair("subject", map[string]interface{}{"role": "admin"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err = refreshIssuer.Refresh(first.AccessToken)
+	if !errors.Is(err, jwtkit.ErrNotRefreshToken) {
+		t.Fatalf("expected access token to be refused got: %v", err)
+	}
+
+	accessVerifier := &jwtkit.JWTVerifier{Resolver: ks, Validator: &jwtkit.Validator{Issuer: "issuer"}}
+	if _, err = accessVerifier.VerifyToken(first.AccessToken); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, err = accessVerifier.VerifyToken(first.RefreshToken); !errors.Is(err, jwtkit.ErrRefreshTokenRefused) {
+		t.Fatalf("expected refresh token to be refused as access token got: %v", err)
+	}
+	refreshJWT, err := jwtkit.GetJWT(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if refreshJWT.Header.Type != jwtkit.TypeRefreshJWT {
+		t.Fatalf("expected typ %s got: %s", jwtkit.TypeRefreshJWT, refreshJWT.Header.Type)
+	}
+	// refresh tokens issued before the typ header existed are told apart by their claims
+	if err = (&jwtkit.Validator{}).Validate(refreshJWT.Payload); !errors.Is(err, jwtkit.ErrRefreshTokenRefused) {
+		t.Fatalf("expected refresh payload to be refused got: %v", err)
+	}
+	if err = (&jwtkit.Validator{AllowRefreshTokens: true}).Validate(refreshJWT.Payload); err != nil {
+		t.Fatalf("expected refresh payload to be allowed got: %v", err)
+	}
+
+	second, err := refreshIssuer.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected refresh token to be rotated")
+	}
+	jwt, err := jwtkit.GetJWT(second.AccessToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if jwt.Payload.Subject != "subject" || jwt.Payload.Claims["role"] != "admin" {
+		t.Fatalf("expected claims to be carried over got: %+v", jwt.Payload)
+	}
+
+	_, err = refreshIssuer.Refresh(first.RefreshToken)
+	if !errors.Is(err, jwtkit.ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse detection got: %v", err)
+	}
+	_, err = refreshIssuer.Refresh(second.RefreshToken)
+	if !errors.Is(err, jwtkit.ErrRefreshFamilyRevoked) {
+		t.Fatalf("expected the whole family to be revoked got: %v", err)
+	}
+}
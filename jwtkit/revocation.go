@@ -0,0 +1,136 @@
+package jwtkit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ilhammhdd/go-toolkit/sqlkit"
+)
+
+const DefaultRevocationTable = "revoked_tokens"
+
+// RevocationTableSchema is the table SQLRevocationStore expects, expired_at uses the token's exp unit.
+const RevocationTableSchema = `CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti VARCHAR(255) NOT NULL PRIMARY KEY,
+	expired_at BIGINT NOT NULL
+)`
+
+var ErrRevoked = errors.New("token is revoked")
+
+// RevocationStore keeps revoked jti only until expiredAt, after that exp rejects the token anyway.
+type RevocationStore interface {
+	Revoke(jti string, expiredAt int64) error
+	IsRevoked(jti string) (bool, error)
+}
+
+// RevokeJWTString revokes the jti of j until its exp, verify j before revoking it.
+func RevokeJWTString(store RevocationStore, j JWTString) error {
+	jwt, err := GetJWT(j)
+	if err != nil {
+		return err
+	}
+	if jwt.Payload.Id == "" {
+		return fmt.Errorf("%w: jti", ErrMissingClaim)
+	}
+	return store.Revoke(jwt.Payload.Id, jwt.Payload.ExpiredAt)
+}
+
+// MemoryRevocationStore compares expiredAt with Clock in TimeUnit, both default like the Validator's.
+type MemoryRevocationStore struct {
+	Clock    Clock
+	TimeUnit TimeUnit
+	mu       sync.Mutex
+	revoked  map[string]int64
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]int64)}
+}
+
+func (mrs *MemoryRevocationStore) Revoke(jti string, expiredAt int64) error {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	now := mrs.TimeUnit.now(mrs.Clock)
+	for revokedJTI, revokedExpiredAt := range mrs.revoked {
+		if revokedExpiredAt <= now {
+			delete(mrs.revoked, revokedJTI)
+		}
+	}
+	if expiredAt != 0 && expiredAt <= now {
+		return nil
+	}
+	mrs.revoked[jti] = expiredAt
+
+	return nil
+}
+
+func (mrs *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	expiredAt, ok := mrs.revoked[jti]
+	if !ok {
+		return false, nil
+	}
+	if expiredAt != 0 && expiredAt <= mrs.TimeUnit.now(mrs.Clock) {
+		delete(mrs.revoked, jti)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (mrs *MemoryRevocationStore) Len() int {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+	return len(mrs.revoked)
+}
+
+// SQLRevocationStore persists revocations across restarts, see RevocationTableSchema.
+type SQLRevocationStore struct {
+	DBO      sqlkit.DBOperation
+	Table    string
+	Clock    Clock
+	TimeUnit TimeUnit
+}
+
+func (srs *SQLRevocationStore) table() string {
+	if srs.Table == "" {
+		return DefaultRevocationTable
+	}
+	return srs.Table
+}
+
+func (srs *SQLRevocationStore) Revoke(jti string, expiredAt int64) error {
+	_, err := srs.DBO.TxCommand([]*sqlkit.TxCmdStmtArgs{
+		{Statement: fmt.Sprintf("DELETE FROM %s WHERE jti = ?", srs.table()), Args: []interface{}{jti}},
+		{Statement: fmt.Sprintf("INSERT INTO %s (jti, expired_at) VALUES (?, ?)", srs.table()), Args: []interface{}{jti, expiredAt}},
+	})
+	return err
+}
+
+func (srs *SQLRevocationStore) IsRevoked(jti string) (bool, error) {
+	row, err := srs.DBO.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE jti = ? AND (expired_at = 0 OR expired_at > ?)", srs.table()), jti, srs.TimeUnit.now(srs.Clock))
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Purge deletes every revocation whose token is already expired.
+func (srs *SQLRevocationStore) Purge() (int64, error) {
+	result, err := srs.DBO.Command(fmt.Sprintf("DELETE FROM %s WHERE expired_at <> 0 AND expired_at <= ?", srs.table()), srs.TimeUnit.now(srs.Clock))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,153 @@
+package jwtkit_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+	"github.com/ilhammhdd/go-toolkit/sqlkit"
+)
+
+type recordedStmt struct {
+	query string
+	args  []driver.Value
+}
+
+// recordingDB is a database/sql driver that records every statement, queries answer count and
+// commands affect rowsAffected rows.
+type recordingDB struct {
+	mu           sync.Mutex
+	stmts        []recordedStmt
+	commits      int
+	count        int64
+	rowsAffected int64
+}
+
+func (rdb *recordingDB) Connect(ctx context.Context) (driver.Conn, error) { return rdb, nil }
+func (rdb *recordingDB) Driver() driver.Driver                            { return nil }
+func (rdb *recordingDB) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{rdb, query}, nil
+}
+func (rdb *recordingDB) Close() error              { return nil }
+func (rdb *recordingDB) Begin() (driver.Tx, error) { return rdb, nil }
+func (rdb *recordingDB) Commit() error {
+	rdb.mu.Lock()
+	defer rdb.mu.Unlock()
+	rdb.commits++
+	return nil
+}
+func (rdb *recordingDB) Rollback() error { return nil }
+
+func (rdb *recordingDB) record(query string, args []driver.Value) {
+	rdb.mu.Lock()
+	defer rdb.mu.Unlock()
+	rdb.stmts = append(rdb.stmts, recordedStmt{query, args})
+}
+
+func (rdb *recordingDB) reset() {
+	rdb.mu.Lock()
+	defer rdb.mu.Unlock()
+	rdb.stmts, rdb.commits = nil, 0
+}
+
+type recordingStmt struct {
+	rdb   *recordingDB
+	query string
+}
+
+func (rs *recordingStmt) Close() error  { return nil }
+func (rs *recordingStmt) NumInput() int { return -1 }
+func (rs *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	rs.rdb.record(rs.query, args)
+	return driver.RowsAffected(rs.rdb.rowsAffected), nil
+}
+func (rs *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rs.rdb.record(rs.query, args)
+	return &countRows{count: rs.rdb.count}, nil
+}
+
+type countRows struct {
+	count int64
+	done  bool
+}
+
+func (cr *countRows) Columns() []string { return []string{"count"} }
+func (cr *countRows) Close() error      { return nil }
+func (cr *countRows) Next(dest []driver.Value) error {
+	if cr.done {
+		return io.EOF
+	}
+	cr.done = true
+	dest[0] = cr.count
+	return nil
+}
+
+func TestSQLRevocationStore(t *testing.T) {
+	frozen := time.Unix(1700000000, 0)
+	testCases := []struct {
+		name     string
+		table    string
+		timeUnit jwtkit.TimeUnit
+		now      int64
+	}{
+		{"milliseconds", "", jwtkit.Milliseconds, frozen.UnixNano() / int64(time.Millisecond)},
+		{"seconds", "tokens", jwtkit.Seconds, frozen.Unix()},
+	}
+
+	for _, tc := range testCases {
+		rdb := &recordingDB{}
+		db := sql.OpenDB(rdb)
+		store := &jwtkit.SQLRevocationStore{DBO: sqlkit.DBOperation{DB: db}, Table: tc.table, Clock: jwtkit.NewFrozenClock(frozen), TimeUnit: tc.timeUnit}
+		table := tc.table
+		if table == "" {
+			table = jwtkit.DefaultRevocationTable
+		}
+
+		err := store.Revoke("jti", tc.now+60)
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		expected := []recordedStmt{
+			{"DELETE FROM " + table + " WHERE jti = ?", []driver.Value{"jti"}},
+			{"INSERT INTO " + table + " (jti, expired_at) VALUES (?, ?)", []driver.Value{"jti", tc.now + 60}},
+		}
+		if !reflect.DeepEqual(rdb.stmts, expected) || rdb.commits != 1 {
+			t.Fatalf("%s expected one tx of %v got: %v with %d commits", tc.name, expected, rdb.stmts, rdb.commits)
+		}
+
+		for _, count := range []int64{0, 1} {
+			rdb.reset()
+			rdb.count = count
+			revoked, err := store.IsRevoked("jti")
+			if err != nil {
+				t.Fatalf("%s error: %v", tc.name, err)
+			}
+			expected = []recordedStmt{
+				{"SELECT COUNT(*) FROM " + table + " WHERE jti = ? AND (expired_at = 0 OR expired_at > ?)", []driver.Value{"jti", tc.now}},
+			}
+			if revoked != (count == 1) || !reflect.DeepEqual(rdb.stmts, expected) {
+				t.Fatalf("%s count %d expected %t with %v got: %t with %v", tc.name, count, count == 1, expected, revoked, rdb.stmts)
+			}
+		}
+
+		rdb.reset()
+		rdb.rowsAffected = 3
+		purged, err := store.Purge()
+		if err != nil {
+			t.Fatalf("%s error: %v", tc.name, err)
+		}
+		expected = []recordedStmt{
+			{"DELETE FROM " + table + " WHERE expired_at <> 0 AND expired_at <= ?", []driver.Value{tc.now}},
+		}
+		if purged != 3 || !reflect.DeepEqual(rdb.stmts, expected) {
+			t.Fatalf("%s expected 3 purged with %v got: %d with %v", tc.name, expected, purged, rdb.stmts)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,427 @@
+package jwtkit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const minRSAKeyBits = 2048
+
+var (
+	ErrUnsupportedAlg       = errors.New("unsupported signing algorithm")
+	ErrAlgorithmKeyMismatch = errors.New("key can not be used with the signing algorithm")
+	ErrInvalidSignature     = errors.New("invalid signature")
+)
+
+type Signer interface {
+	Algorithm() string
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+type Verifier interface {
+	Algorithm() string
+	Verify(signingInput []byte, signature []byte) error
+}
+
+// VerifierResolver picks the Verifier for a token based on its header, it must never trust
+// the header alone and should reject any algorithm the key was not meant for.
+type VerifierResolver interface {
+	ResolveVerifier(header *Header) (Verifier, error)
+}
+
+type VerifierResolverFunc func(header *Header) (Verifier, error)
+
+func (vrf VerifierResolverFunc) ResolveVerifier(header *Header) (Verifier, error) {
+	return vrf(header)
+}
+
+func algorithmHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case AlgorithmHS256, AlgorithmRS256, AlgorithmPS256, AlgorithmES256:
+		return crypto.SHA256, true
+	case AlgorithmHS384, AlgorithmRS384, AlgorithmES384:
+		return crypto.SHA384, true
+	case AlgorithmHS512, AlgorithmRS512, AlgorithmES512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+func algorithmCurve(alg string) elliptic.Curve {
+	switch alg {
+	case AlgorithmES256:
+		return elliptic.P256()
+	case AlgorithmES384:
+		return elliptic.P384()
+	case AlgorithmES512:
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+func hashed(hash crypto.Hash, signingInput []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(signingInput)
+	return hasher.Sum(nil)
+}
+
+// AlgorithmFromKey returns the algorithm a key signs with when none is given explicitly,
+// RSA keys default to RS256 and HMAC secrets to HS256.
+func AlgorithmFromKey(key interface{}) (string, error) {
+	switch k := key.(type) {
+	case []byte:
+		return AlgorithmHS256, nil
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return AlgorithmRS256, nil
+	case *ecdsa.PrivateKey:
+		return AlgorithmFromKey(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return AlgorithmES256, nil
+		case elliptic.P384():
+			return AlgorithmES384, nil
+		case elliptic.P521():
+			return AlgorithmES512, nil
+		}
+		return "", fmt.Errorf("%w: unsupported curve %s", ErrAlgorithmKeyMismatch, k.Curve.Params().Name)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return AlgorithmEdDSA, nil
+	case crypto.Signer:
+		return AlgorithmFromKey(k.Public())
+	default:
+		return "", fmt.Errorf("%w: unsupported key type %T", ErrAlgorithmKeyMismatch, key)
+	}
+}
+
+func SignerFromKey(key interface{}) (Signer, error) {
+	alg, err := AlgorithmFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(alg, key)
+}
+
+// NewSigner accepts []byte secrets, the standard library private keys and any other crypto.Signer
+// such as a KMS or HSM backed key.
+func NewSigner(alg string, key interface{}) (Signer, error) {
+	if signer, ok := key.(crypto.Signer); ok && !isConcretePrivateKey(key) {
+		return newCryptoSigner(alg, signer)
+	}
+
+	switch alg {
+	case AlgorithmHS256, AlgorithmHS384, AlgorithmHS512:
+		return newHMACKey(alg, key)
+	case AlgorithmRS256, AlgorithmRS384, AlgorithmRS512, AlgorithmPS256:
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires *rsa.PrivateKey got %T", ErrAlgorithmKeyMismatch, alg, key)
+		}
+		if privateKey.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%w: RSA key must be at least %d bits", ErrAlgorithmKeyMismatch, minRSAKeyBits)
+		}
+		return &rsaSigner{alg: alg, privateKey: privateKey}, nil
+	case AlgorithmES256, AlgorithmES384, AlgorithmES512:
+		privateKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires *ecdsa.PrivateKey got %T", ErrAlgorithmKeyMismatch, alg, key)
+		}
+		if privateKey.Curve != algorithmCurve(alg) {
+			return nil, fmt.Errorf("%w: %s requires curve %s", ErrAlgorithmKeyMismatch, alg, algorithmCurve(alg).Params().Name)
+		}
+		return &ecdsaSigner{alg: alg, privateKey: privateKey}, nil
+	case AlgorithmEdDSA:
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok || len(privateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("%w: %s requires ed25519.PrivateKey got %T", ErrAlgorithmKeyMismatch, alg, key)
+		}
+		return &ed25519Signer{privateKey: privateKey}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+	}
+}
+
+// NewVerifier only accepts public keys for asymmetric algorithms and only secrets for HMAC,
+// so a token claiming HS256 can never be checked against a public key.
+func NewVerifier(alg string, key interface{}) (Verifier, error) {
+	if signer, ok := key.(crypto.Signer); ok && !isConcretePrivateKey(key) {
+		key = signer.Public()
+	}
+
+	switch alg {
+	case AlgorithmHS256, AlgorithmHS384, AlgorithmHS512:
+		return newHMACKey(alg, key)
+	case AlgorithmRS256, AlgorithmRS384, AlgorithmRS512, AlgorithmPS256:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if privateKey, isPrivate := key.(*rsa.PrivateKey); isPrivate {
+			publicKey, ok = &privateKey.PublicKey, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires *rsa.PublicKey got %T", ErrAlgorithmKeyMismatch, alg, key)
+		}
+		if publicKey.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%w: RSA key must be at least %d bits", ErrAlgorithmKeyMismatch, minRSAKeyBits)
+		}
+		return &rsaVerifier{alg: alg, publicKey: publicKey}, nil
+	case AlgorithmES256, AlgorithmES384, AlgorithmES512:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if privateKey, isPrivate := key.(*ecdsa.PrivateKey); isPrivate {
+			publicKey, ok = &privateKey.PublicKey, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires *ecdsa.PublicKey got %T", ErrAlgorithmKeyMismatch, alg, key)
+		}
+		if publicKey.Curve != algorithmCurve(alg) {
+			return nil, fmt.Errorf("%w: %s requires curve %s", ErrAlgorithmKeyMismatch, alg, algorithmCurve(alg).Params().Name)
+		}
+		return &ecdsaVerifier{alg: alg, publicKey: publicKey}, nil
+	case AlgorithmEdDSA:
+		publicKey, ok := key.(ed25519.PublicKey)
+		if privateKey, isPrivate := key.(ed25519.PrivateKey); isPrivate && len(privateKey) == ed25519.PrivateKeySize {
+			publicKey, ok = privateKey.Public().(ed25519.PublicKey), true
+		}
+		if !ok || len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: %s requires ed25519.PublicKey got %T", ErrAlgorithmKeyMismatch, alg, key)
+		}
+		return &ed25519Verifier{publicKey: publicKey}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+	}
+}
+
+// KeyVerifierResolver resolves the verifier from the token's alg header, refusing any
+// algorithm that does not belong to the key family.
+func KeyVerifierResolver(key interface{}) VerifierResolver {
+	return VerifierResolverFunc(func(header *Header) (Verifier, error) {
+		return NewVerifier(header.Algorithm, key)
+	})
+}
+
+func isConcretePrivateKey(key interface{}) bool {
+	switch key.(type) {
+	case []byte, *ecdsa.PrivateKey, *rsa.PrivateKey, ed25519.PrivateKey:
+		return true
+	default:
+		return false
+	}
+}
+
+type hmacKey struct {
+	alg    string
+	hash   crypto.Hash
+	secret []byte
+}
+
+func newHMACKey(alg string, key interface{}) (*hmacKey, error) {
+	secret, ok := key.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s requires []byte secret got %T", ErrAlgorithmKeyMismatch, alg, key)
+	}
+	hash, _ := algorithmHash(alg)
+	if len(secret) < hash.Size() {
+		return nil, fmt.Errorf("%w: %s secret must be at least %d bytes", ErrAlgorithmKeyMismatch, alg, hash.Size())
+	}
+	return &hmacKey{alg: alg, hash: hash, secret: secret}, nil
+}
+
+func (hk *hmacKey) Algorithm() string { return hk.alg }
+
+func (hk *hmacKey) Sign(signingInput []byte) ([]byte, error) {
+	mac := hmac.New(hk.hash.New, hk.secret)
+	mac.Write(signingInput)
+	return mac.Sum(nil), nil
+}
+
+func (hk *hmacKey) Verify(signingInput []byte, signature []byte) error {
+	expected, err := hk.Sign(signingInput)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type rsaSigner struct {
+	alg        string
+	privateKey *rsa.PrivateKey
+}
+
+func (rs *rsaSigner) Algorithm() string { return rs.alg }
+
+func (rs *rsaSigner) Sign(signingInput []byte) ([]byte, error) {
+	hash, _ := algorithmHash(rs.alg)
+	if rs.alg == AlgorithmPS256 {
+		return rsa.SignPSS(rand.Reader, rs.privateKey, hash, hashed(hash, signingInput), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	}
+	return rsa.SignPKCS1v15(rand.Reader, rs.privateKey, hash, hashed(hash, signingInput))
+}
+
+type rsaVerifier struct {
+	alg       string
+	publicKey *rsa.PublicKey
+}
+
+func (rv *rsaVerifier) Algorithm() string { return rv.alg }
+
+func (rv *rsaVerifier) Verify(signingInput []byte, signature []byte) error {
+	hash, _ := algorithmHash(rv.alg)
+	var err error
+	if rv.alg == AlgorithmPS256 {
+		err = rsa.VerifyPSS(rv.publicKey, hash, hashed(hash, signingInput), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	} else {
+		err = rsa.VerifyPKCS1v15(rv.publicKey, hash, hashed(hash, signingInput), signature)
+	}
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type ecdsaSigner struct {
+	alg        string
+	privateKey *ecdsa.PrivateKey
+}
+
+func (es *ecdsaSigner) Algorithm() string { return es.alg }
+
+func (es *ecdsaSigner) Sign(signingInput []byte) ([]byte, error) {
+	hash, _ := algorithmHash(es.alg)
+	r, s, err := ecdsa.Sign(rand.Reader, es.privateKey, hashed(hash, signingInput))
+	if err != nil {
+		return nil, err
+	}
+
+	keySize := curveKeySize(es.privateKey.Curve)
+	signature := make([]byte, 2*keySize)
+	r.FillBytes(signature[:keySize])
+	s.FillBytes(signature[keySize:])
+
+	return signature, nil
+}
+
+type ecdsaVerifier struct {
+	alg       string
+	publicKey *ecdsa.PublicKey
+}
+
+func (ev *ecdsaVerifier) Algorithm() string { return ev.alg }
+
+func (ev *ecdsaVerifier) Verify(signingInput []byte, signature []byte) error {
+	r, s, err := splitECDSASignature(ev.publicKey.Curve, signature)
+	if err != nil {
+		return err
+	}
+
+	hash, _ := algorithmHash(ev.alg)
+	if !ecdsa.Verify(ev.publicKey, hashed(hash, signingInput), r, s) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func curveKeySize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+func splitECDSASignature(curve elliptic.Curve, signature []byte) (*big.Int, *big.Int, error) {
+	keySize := curveKeySize(curve)
+	if len(signature) != 2*keySize {
+		return nil, nil, fmt.Errorf("%w: expected %d bytes got %d", ErrInvalidSignature, 2*keySize, len(signature))
+	}
+	r := new(big.Int).SetBytes(signature[:keySize])
+	s := new(big.Int).SetBytes(signature[keySize:])
+
+	return r, s, nil
+}
+
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (es *ed25519Signer) Algorithm() string { return AlgorithmEdDSA }
+
+func (es *ed25519Signer) Sign(signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(es.privateKey, signingInput), nil
+}
+
+type ed25519Verifier struct {
+	publicKey ed25519.PublicKey
+}
+
+func (ev *ed25519Verifier) Algorithm() string { return AlgorithmEdDSA }
+
+func (ev *ed25519Verifier) Verify(signingInput []byte, signature []byte) error {
+	if !ed25519.Verify(ev.publicKey, signingInput, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// cryptoSigner signs through an opaque crypto.Signer, ECDSA ASN.1 signatures are converted to R||S.
+type cryptoSigner struct {
+	alg    string
+	signer crypto.Signer
+}
+
+func newCryptoSigner(alg string, signer crypto.Signer) (*cryptoSigner, error) {
+	if alg == AlgorithmHS256 || alg == AlgorithmHS384 || alg == AlgorithmHS512 {
+		return nil, fmt.Errorf("%w: %s requires []byte secret got %T", ErrAlgorithmKeyMismatch, alg, signer)
+	}
+	_, err := NewVerifier(alg, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &cryptoSigner{alg: alg, signer: signer}, nil
+}
+
+func (cs *cryptoSigner) Algorithm() string { return cs.alg }
+
+func (cs *cryptoSigner) Sign(signingInput []byte) ([]byte, error) {
+	if cs.alg == AlgorithmEdDSA {
+		return cs.signer.Sign(rand.Reader, signingInput, crypto.Hash(0))
+	}
+
+	hash, _ := algorithmHash(cs.alg)
+	var opts crypto.SignerOpts = hash
+	if cs.alg == AlgorithmPS256 {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	}
+	signature, err := cs.signer.Sign(rand.Reader, hashed(hash, signingInput), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := cs.signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return signature, nil
+	}
+	var asn1Signature struct {
+		R, S *big.Int
+	}
+	_, err = asn1.Unmarshal(signature, &asn1Signature)
+	if err != nil {
+		return nil, err
+	}
+	keySize := curveKeySize(publicKey.Curve)
+	rawSignature := make([]byte, 2*keySize)
+	asn1Signature.R.FillBytes(rawSignature[:keySize])
+	asn1Signature.S.FillBytes(rawSignature[keySize:])
+
+	return rawSignature, nil
+}
@@ -0,0 +1,119 @@
+package jwtkit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestSignerVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	secret := []byte(strings.Repeat("s", 64))
+
+	testCases := []struct {
+		alg        string
+		signingKey interface{}
+		verifyKey  interface{}
+	}{
+		{jwtkit.AlgorithmHS256, secret, secret},
+		{jwtkit.AlgorithmHS384, secret, secret},
+		{jwtkit.AlgorithmHS512, secret, secret},
+		{jwtkit.AlgorithmRS256, rsaKey, &rsaKey.PublicKey},
+		{jwtkit.AlgorithmRS384, rsaKey, &rsaKey.PublicKey},
+		{jwtkit.AlgorithmRS512, rsaKey, &rsaKey.PublicKey},
+		{jwtkit.AlgorithmPS256, rsaKey, &rsaKey.PublicKey},
+		{jwtkit.AlgorithmES384, p384Key, &p384Key.PublicKey},
+		{jwtkit.AlgorithmES512, p521Key, &p521Key.PublicKey},
+		{jwtkit.AlgorithmEdDSA, edPrivate, edPublic},
+	}
+
+	for i := range testCases {
+		signer, err := jwtkit.NewSigner(testCases[i].alg, testCases[i].signingKey)
+		if err != nil {
+			t.Fatalf("alg: %s error: %v", testCases[i].alg, err)
+		}
+		j, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+		if err != nil {
+			t.Fatalf("alg: %s error: %v", testCases[i].alg, err)
+		}
+
+		ok, err := jwtkit.VerifyJWTStringWith(jwtkit.KeyVerifierResolver(testCases[i].verifyKey), j)
+		if !ok || err != nil {
+			t.Fatalf("alg: %s expected valid got: %t %v", testCases[i].alg, ok, err)
+		}
+
+		jwt, err := jwtkit.GetJWT(j)
+		if err != nil {
+			t.Fatalf("alg: %s error: %v", testCases[i].alg, err)
+		}
+		if jwt.Header.Algorithm != testCases[i].alg {
+			t.Fatalf("alg: %s got header alg: %s", testCases[i].alg, jwt.Header.Algorithm)
+		}
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	signer, err := jwtkit.NewSigner(jwtkit.AlgorithmRS256, rsaKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	j, err := jwtkit.JWTExpiration(60000).GenerateJWTStringWith(signer, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	// re-sign the same payload with HS256 using the public key bytes as the secret
+	jwtParts := strings.Split(string(j), ".")
+	header, _ := json.Marshal(map[string]string{"alg": jwtkit.AlgorithmHS256, "typ": "JWT"})
+	publicKeyAsSecret := rsaKey.PublicKey.N.Bytes()
+	hmacSigner, err := jwtkit.NewSigner(jwtkit.AlgorithmHS256, publicKeyAsSecret)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + jwtParts[1]
+	signature, err := hmacSigner.Sign([]byte(signingInput))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	forged := jwtkit.JWTString(signingInput + "." + base64.RawURLEncoding.EncodeToString(signature))
+
+	ok, err := jwtkit.VerifyJWTStringWith(jwtkit.KeyVerifierResolver(&rsaKey.PublicKey), forged)
+	if ok || !errors.Is(err, jwtkit.ErrAlgorithmKeyMismatch) {
+		t.Fatalf("expected algorithm key mismatch got: %t %v", ok, err)
+	}
+
+	none, _ := json.Marshal(map[string]string{"alg": "none", "typ": "JWT"})
+	unsigned := jwtkit.JWTString(base64.RawURLEncoding.EncodeToString(none) + "." + jwtParts[1] + ".")
+	ok, err = jwtkit.VerifyJWTStringWith(jwtkit.KeyVerifierResolver(&rsaKey.PublicKey), unsigned)
+	if ok || !errors.Is(err, jwtkit.ErrUnsupportedAlg) {
+		t.Fatalf("expected unsupported alg got: %t %v", ok, err)
+	}
+}
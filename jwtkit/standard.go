@@ -0,0 +1,184 @@
+package jwtkit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+var registeredClaimNames = []string{"jti", "aud", "iss", "sub", "iat", "exp", "nbf"}
+
+// RegisteredClaimNames are the claims of RegisteredClaims, every other claim of a standard token is custom.
+func RegisteredClaimNames() []string {
+	return append([]string(nil), registeredClaimNames...)
+}
+
+// GenerateStandardJWTString generates an RFC 7519 compact JWS signed with ES256, unlike
+// GenerateSignedJWTString which generates the legacy toolkit format.
+func (je JWTExpiration) GenerateStandardJWTString(encrypt *ECDSA, audience string, issuer string, claims ...*map[string]interface{}) (JWTString, error) {
+	signer, err := encrypt.Signer()
+	if err != nil {
+		return "", err
+	}
+	return je.GenerateJWTStringWith(signer, audience, issuer, claims...)
+}
+
+// GenerateJWTStringWith generates an RFC 7519 compact JWS, the alg header is taken from the signer.
+// Unlike the legacy format iat and exp are NumericDate seconds, the unit a zero Validator expects.
+func (je JWTExpiration) GenerateJWTStringWith(signer Signer, audience string, issuer string, claims ...*map[string]interface{}) (JWTString, error) {
+	var customClaims map[string]interface{}
+	if len(claims) != 0 {
+		customClaims = *claims[0]
+	}
+	return je.StandardIssuer(signer, audience, issuer).Issue("", customClaims)
+}
+
+// StandardIssuer is the Issuer behind GenerateJWTStringWith, set its Clock to freeze time or its
+// TimeUnit to Milliseconds for consumers of the legacy unit.
+func (je JWTExpiration) StandardIssuer(signer Signer, audience string, issuer string) *Issuer {
+	return &Issuer{
+		Signer:     signer,
+		Issuer:     issuer,
+		Audience:   NewAudience(audience),
+		Expiration: je,
+		TimeUnit:   Seconds,
+	}
+}
+
+func signCompact(signer Signer, header *Header, payload []byte) (JWTString, error) {
+	header.Algorithm = signer.Algorithm()
+	if identified, ok := signer.(interface{ KeyID() string }); ok {
+		header.KeyID = identified.KeyID()
+	}
+	jsonHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(jsonHeader) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return JWTString(signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)), nil
+}
+
+// VerifyJWTStringWith verifies a standard token, the resolver picks the verifier from the token header.
+func VerifyJWTStringWith(resolver VerifierResolver, j JWTString) (bool, error) {
+	pt, err := parseToken(j)
+	if err != nil {
+		return false, err
+	}
+
+	return verifyStandardJWTString(resolver, pt)
+}
+
+// marshalFlat puts the custom claims next to the registered claims, registered claims win on conflict.
+func (p *Payload) marshalFlat() ([]byte, error) {
+	return json.Marshal(p.FlatClaims())
+}
+
+// FlatClaims is the payload as a standard token carries it, custom claims next to the registered ones.
+func (p *Payload) FlatClaims() map[string]interface{} {
+	flat := make(map[string]interface{}, len(p.Claims)+len(registeredClaimNames))
+	for key, val := range p.Claims {
+		flat[key] = val
+	}
+
+	if p.Id != "" {
+		flat["jti"] = p.Id
+	}
+	if audiences := p.audiences(); len(audiences) != 0 {
+		flat["aud"] = audiences
+	}
+	if p.Issuer != "" {
+		flat["iss"] = p.Issuer
+	}
+	if p.Subject != "" {
+		flat["sub"] = p.Subject
+	}
+	if p.IssuedAt != 0 {
+		flat["iat"] = p.IssuedAt
+	}
+	if p.ExpiredAt != 0 {
+		flat["exp"] = p.ExpiredAt
+	}
+	if p.NotBefore != 0 {
+		flat["nbf"] = p.NotBefore
+	}
+
+	return flat
+}
+
+func (p *Payload) unmarshalFlat(data []byte) error {
+	var registered Payload
+	err := json.Unmarshal(data, &registered)
+	if err != nil {
+		return err
+	}
+
+	var flat map[string]interface{}
+	err = json.Unmarshal(data, &flat)
+	if err != nil {
+		return err
+	}
+	for _, name := range registeredClaimNames {
+		delete(flat, name)
+	}
+
+	*p = registered
+	p.Claims = nil
+	if len(flat) != 0 {
+		p.Claims = flat
+	}
+
+	return nil
+}
+
+func verifyStandardJWTString(resolver VerifierResolver, pt *parsedToken) (bool, error) {
+	if pt.format != FormatStandard {
+		return false, &ParseError{SegmentHeader, ErrUnsupportedAlg, "legacy token"}
+	}
+	verifier, err := resolver.ResolveVerifier(pt.header)
+	if err != nil {
+		return false, err
+	}
+	if verifier.Algorithm() != pt.header.Algorithm {
+		return false, fmt.Errorf("%w: token alg %q verifier alg %q", ErrAlgorithmKeyMismatch, pt.header.Algorithm, verifier.Algorithm())
+	}
+
+	err = verifier.Verify(pt.signingInput(), pt.signature)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func getStandardJWT(pt *parsedToken) (*JWT, error) {
+	var jwtPayload Payload
+	err := jwtPayload.unmarshalFlat(pt.payload)
+	if err != nil {
+		return nil, &ParseError{SegmentPayload, ErrMalformed, err.Error()}
+	}
+
+	header := pt.header
+	jwtSignature := &Signature{Raw: pt.signature}
+	if curve := algorithmCurve(header.Algorithm); curve != nil {
+		r, s, err := splitECDSASignature(curve, pt.signature)
+		if err != nil {
+			return nil, &ParseError{SegmentSignature, ErrMalformed, err.Error()}
+		}
+		hash, _ := algorithmHash(header.Algorithm)
+		jwtSignature.Hashed = hashed(hash, pt.signingInput())
+		jwtSignature.R = r
+		jwtSignature.S = s
+	}
+
+	return &JWT{
+		Header:    header,
+		Payload:   &jwtPayload,
+		Signature: jwtSignature,
+	}, nil
+}
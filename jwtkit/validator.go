@@ -0,0 +1,178 @@
+package jwtkit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrExpired           = errors.New("token is expired")
+	ErrNotYetValid       = errors.New("token is not valid yet")
+	ErrIssuedInFuture    = errors.New("token is issued in the future")
+	ErrTokenTooOld       = errors.New("token is too old")
+	ErrIssuerMismatch    = errors.New("token issuer mismatch")
+	ErrAudienceMismatch  = errors.New("token audience mismatch")
+	ErrMissingClaim      = errors.New("token is missing a required claim")
+	ErrMissingExpiration = errors.New("token has no expiration")
+)
+
+// Audience is a single string when it holds one value and an array otherwise, as RFC 7519 allows both.
+type Audience []string
+
+func NewAudience(audiences ...string) Audience {
+	var result Audience
+	for _, audience := range audiences {
+		if audience != "" {
+			result = append(result, audience)
+		}
+	}
+	return result
+}
+
+func (a Audience) Contains(audience string) bool {
+	for i := range a {
+		if a[i] == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = NewAudience(single)
+		return nil
+	}
+
+	var multiple []string
+	err := json.Unmarshal(data, &multiple)
+	if err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = multiple
+
+	return nil
+}
+
+// Validator checks the registered claims of an already verified payload, times are compared
+// with Leeway to tolerate clock skew between issuer and verifier. TimeUnit must match the issuer's.
+type Validator struct {
+	Clock             Clock
+	TimeUnit          TimeUnit
+	Leeway            time.Duration
+	Issuer            string
+	Audience          string
+	MaxAge            time.Duration
+	RequiredClaims    []string
+	RequireExpiration bool
+	// Revocations is consulted after every other check, tokens without jti are refused when it is set
+	Revocations RevocationStore
+	// AllowRefreshTokens accepts refresh tokens of a RefreshIssuer, only set it where they are expected
+	// such as introspection and revocation
+	AllowRefreshTokens bool
+}
+
+func (v *Validator) Validate(p *Payload) error {
+	now := v.TimeUnit.now(v.Clock)
+	leeway := v.TimeUnit.Duration(v.Leeway)
+
+	if p.ExpiredAt == 0 && v.RequireExpiration {
+		return ErrMissingExpiration
+	}
+	if p.ExpiredAt != 0 && now >= p.ExpiredAt+leeway {
+		return fmt.Errorf("%w: expired at %d", ErrExpired, p.ExpiredAt)
+	}
+	if p.NotBefore != 0 && now < p.NotBefore-leeway {
+		return fmt.Errorf("%w: not before %d", ErrNotYetValid, p.NotBefore)
+	}
+	if p.IssuedAt != 0 && now < p.IssuedAt-leeway {
+		return fmt.Errorf("%w: issued at %d", ErrIssuedInFuture, p.IssuedAt)
+	}
+	if v.MaxAge > 0 {
+		if p.IssuedAt == 0 {
+			return fmt.Errorf("%w: iat", ErrMissingClaim)
+		}
+		if now > p.IssuedAt+v.TimeUnit.Duration(v.MaxAge)+leeway {
+			return fmt.Errorf("%w: issued at %d", ErrTokenTooOld, p.IssuedAt)
+		}
+	}
+	if v.Issuer != "" && p.Issuer != v.Issuer {
+		return fmt.Errorf("%w: got %q", ErrIssuerMismatch, p.Issuer)
+	}
+	if v.Audience != "" && !p.audiences().Contains(v.Audience) {
+		return fmt.Errorf("%w: got %q", ErrAudienceMismatch, []string(p.audiences()))
+	}
+	for _, claim := range v.RequiredClaims {
+		if !p.hasClaim(claim) {
+			return fmt.Errorf("%w: %s", ErrMissingClaim, claim)
+		}
+	}
+	if p.IsRefreshToken() && !v.AllowRefreshTokens {
+		return ErrRefreshTokenRefused
+	}
+	if v.Revocations != nil {
+		if p.Id == "" {
+			return fmt.Errorf("%w: jti", ErrMissingClaim)
+		}
+		revoked, err := v.Revocations.IsRevoked(p.Id)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return fmt.Errorf("%w: jti %s", ErrRevoked, p.Id)
+		}
+	}
+
+	return nil
+}
+
+// ValidateJWTString validates the claims without verifying the signature, verify the token first.
+func (v *Validator) ValidateJWTString(j JWTString) error {
+	jwt, err := GetJWT(j)
+	if err != nil {
+		return err
+	}
+	return v.Validate(jwt.Payload)
+}
+
+func (p *Payload) hasClaim(claim string) bool {
+	switch claim {
+	case "jti":
+		return p.Id != ""
+	case "aud":
+		return len(p.audiences()) != 0
+	case "iss":
+		return p.Issuer != ""
+	case "sub":
+		return p.Subject != ""
+	case "iat":
+		return p.IssuedAt != 0
+	case "exp":
+		return p.ExpiredAt != 0
+	case "nbf":
+		return p.NotBefore != 0
+	default:
+		_, ok := p.Claims[claim]
+		return ok
+	}
+}
+
+func newJTI() (string, error) {
+	uuidRand, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return uuidRand.String(), nil
+}
@@ -0,0 +1,257 @@
+package jwtkit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+func TestValidator(t *testing.T) {
+	now := time.Now().Unix()
+	minute := int64(60)
+
+	validator := &jwtkit.Validator{
+		Leeway:         5 * time.Second,
+		Issuer:         "issuer",
+		Audience:       "audience",
+		MaxAge:         time.Hour,
+		RequiredClaims: []string{"sub", "role"},
+	}
+	valid := func() *jwtkit.Payload {
+		return &jwtkit.Payload{
+			Id:        "jti",
+			Audiences: jwtkit.NewAudience("other", "audience"),
+			Issuer:    "issuer",
+			Subject:   "subject",
+			IssuedAt:  now - minute,
+			ExpiredAt: now + minute,
+			Claims:    map[string]interface{}{"role": "admin"},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(p *jwtkit.Payload)
+		expected error
+	}{
+		{"valid", func(p *jwtkit.Payload) {}, nil},
+		{"expired within leeway", func(p *jwtkit.Payload) { p.ExpiredAt = now - 1 }, nil},
+		{"expired", func(p *jwtkit.Payload) { p.ExpiredAt = now - minute }, jwtkit.ErrExpired},
+		{"not yet valid", func(p *jwtkit.Payload) { p.NotBefore = now + minute }, jwtkit.ErrNotYetValid},
+		{"issued in future", func(p *jwtkit.Payload) { p.IssuedAt = now + minute }, jwtkit.ErrIssuedInFuture},
+		{"too old", func(p *jwtkit.Payload) { p.IssuedAt = now - 2*60*minute }, jwtkit.ErrTokenTooOld},
+		{"issuer", func(p *jwtkit.Payload) { p.Issuer = "evil" }, jwtkit.ErrIssuerMismatch},
+		{"single audience", func(p *jwtkit.Payload) { p.Audiences, p.Audience = nil, "audience" }, nil},
+		{"audience", func(p *jwtkit.Payload) { p.Audiences = jwtkit.NewAudience("other") }, jwtkit.ErrAudienceMismatch},
+		{"custom claim", func(p *jwtkit.Payload) { p.Claims = nil }, jwtkit.ErrMissingClaim},
+		{"registered claim", func(p *jwtkit.Payload) { p.Subject = "" }, jwtkit.ErrMissingClaim},
+	}
+
+	for i := range testCases {
+		payload := valid()
+		testCases[i].modify(payload)
+		err := validator.Validate(payload)
+		if testCases[i].expected == nil && err != nil || !errors.Is(err, testCases[i].expected) {
+			t.Fatalf("case: %s expected: %v got: %v", testCases[i].name, testCases[i].expected, err)
+		}
+	}
+}
+
+func TestAudienceJSON(t *testing.T) {
+	testCases := []struct {
+		json     string
+		expected jwtkit.Audience
+	}{
+		{`"a"`, jwtkit.Audience{"a"}},
+		{`["a","b"]`, jwtkit.Audience{"a", "b"}},
+	}
+
+	for i := range testCases {
+		var audience jwtkit.Audience
+		err := json.Unmarshal([]byte(testCases[i].json), &audience)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if len(audience) != len(testCases[i].expected) || !audience.Contains(testCases[i].expected[0]) {
+			t.Fatalf("json: %s got: %v", testCases[i].json, audience)
+		}
+		marshaled, err := json.Marshal(audience)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if string(marshaled) != testCases[i].json {
+			t.Fatalf("expected: %s got: %s", testCases[i].json, marshaled)
+		}
+	}
+}
+
+func TestPayloadAudienceJSON(t *testing.T) {
+	testCases := []struct {
+		payload  jwtkit.Payload
+		json     string
+		audience string
+	}{
+		{jwtkit.Payload{Audience: "a"}, `{"aud":"a"}`, "a"},
+		{jwtkit.Payload{Audiences: jwtkit.NewAudience("a", "b")}, `{"aud":["a","b"]}`, "a"},
+	}
+
+	for i := range testCases {
+		marshaled, err := json.Marshal(testCases[i].payload)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if string(marshaled) != testCases[i].json {
+			t.Fatalf("expected: %s got: %s", testCases[i].json, marshaled)
+		}
+		var payload jwtkit.Payload
+		err = json.Unmarshal(marshaled, &payload)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if payload.Audience != testCases[i].audience || !payload.Audiences.Contains(testCases[i].audience) {
+			t.Fatalf("json: %s got: %q %v", marshaled, payload.Audience, payload.Audiences)
+		}
+	}
+}
+
+type testClaims struct {
+	jwtkit.RegisteredClaims
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes"`
+}
+
+func TestSignParseAndVerify(t *testing.T) {
+	ks := jwtkit.NewKeySet()
+	ks.Add("hmac", []byte("a secret that is long enough for HS256"))
+	ks.Activate("hmac")
+	signer, err := ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	issuer := &jwtkit.Issuer{Signer: signer, Issuer: "issuer", Audience: jwtkit.NewAudience("audience"), Expiration: 60000}
+
+	claims := &testClaims{Role: "admin", Scopes: []string{"read", "write"}}
+	claims.Subject = "subject"
+	j, err := jwtkit.Sign(issuer, claims)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if claims.Id == "" || claims.ExpiredAt == 0 {
+		t.Fatalf("expected registered claims to be filled got: %+v", claims.RegisteredClaims)
+	}
+	if claims.ExpiredAt-claims.IssuedAt != 60 {
+		t.Fatalf("expected NumericDate seconds by default got: %+v", claims.RegisteredClaims)
+	}
+
+	parsed, err := jwtkit.ParseAndVerify[testClaims](j, ks, &jwtkit.Validator{Issuer: "issuer", Audience: "audience"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if parsed.Role != "admin" || len(parsed.Scopes) != 2 || parsed.Subject != "subject" || parsed.Id != claims.Id {
+		t.Fatalf("unexpected claims: %+v", parsed)
+	}
+
+	_, err = jwtkit.ParseAndVerify[testClaims](j, ks, &jwtkit.Validator{Audience: "other"})
+	if !errors.Is(err, jwtkit.ErrAudienceMismatch) {
+		t.Fatalf("expected audience mismatch got: %v", err)
+	}
+
+	expired := &testClaims{RegisteredClaims: jwtkit.RegisteredClaims{ExpiredAt: time.Now().Add(-time.Hour).Unix()}}
+	j, err = jwtkit.Sign(issuer, expired)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	_, err = jwtkit.ParseAndVerify[testClaims](j, ks, nil)
+	if !errors.Is(err, jwtkit.ErrExpired) {
+		t.Fatalf("expected expired got: %v", err)
+	}
+}
+
+func TestNilValidator(t *testing.T) {
+	encrypt := newTestECDSA(t)
+	j, err := jwtkit.JWTExpiration(60000).GenerateStandardJWTString(encrypt, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err = jwtkit.ParseAndVerify[testClaims](j, encrypt, nil)
+	if err != nil {
+		t.Fatalf("ParseAndVerify expected a fresh standard token to verify got: %v", err)
+	}
+	_, err = (&jwtkit.JWTVerifier{Resolver: encrypt}).VerifyToken(j)
+	if err != nil {
+		t.Fatalf("JWTVerifier expected a fresh standard token to verify got: %v", err)
+	}
+}
+
+func TestValidatorRevocations(t *testing.T) {
+	store := jwtkit.NewMemoryRevocationStore()
+	validator := &jwtkit.Validator{Revocations: store}
+	now := time.Now().Unix()
+
+	payload := &jwtkit.Payload{Id: "jti", ExpiredAt: now + 60}
+	if err := validator.Validate(payload); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	store.Revoke(payload.Id, payload.ExpiredAt)
+	if err := validator.Validate(payload); !errors.Is(err, jwtkit.ErrRevoked) {
+		t.Fatalf("expected revoked got: %v", err)
+	}
+
+	store.Revoke("expired", now-1)
+	if store.Len() != 1 {
+		t.Fatalf("expected already expired token to not be stored got: %d", store.Len())
+	}
+}
+
+func TestClockAndTimeUnit(t *testing.T) {
+	ks := jwtkit.NewKeySet()
+	err := ks.Add("kid", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	ks.Activate("kid")
+	signer, err := ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	clock := jwtkit.NewFrozenClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	testCases := []struct {
+		unit     jwtkit.TimeUnit
+		expected int64
+	}{
+		{jwtkit.Seconds, clock.Now().Unix() + 60},
+		{jwtkit.Milliseconds, clock.Now().UnixMilli() + 60000},
+	}
+
+	for _, tc := range testCases {
+		issuer := &jwtkit.Issuer{Signer: signer, Expiration: 60000, Clock: clock, TimeUnit: tc.unit}
+		validator := &jwtkit.Validator{Clock: clock, TimeUnit: tc.unit, Leeway: 5 * time.Second}
+
+		claims := &testClaims{}
+		j, err := jwtkit.Sign(issuer, claims)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if claims.ExpiredAt != tc.expected {
+			t.Fatalf("unit %d expected exp %d got: %d", tc.unit, tc.expected, claims.ExpiredAt)
+		}
+
+		clock.Advance(64 * time.Second)
+		_, err = jwtkit.ParseAndVerify[testClaims](j, ks, validator)
+		if err != nil {
+			t.Fatalf("unit %d expected valid within leeway got: %v", tc.unit, err)
+		}
+		clock.Advance(time.Second)
+		_, err = jwtkit.ParseAndVerify[testClaims](j, ks, validator)
+		if !errors.Is(err, jwtkit.ErrExpired) {
+			t.Fatalf("unit %d expected expired got: %v", tc.unit, err)
+		}
+		clock.Advance(-65 * time.Second)
+	}
+}
@@ -0,0 +1,52 @@
+package jwtkit
+
+// TokenVerifier verifies the signature and validates the claims of a token in one pass.
+type TokenVerifier interface {
+	VerifyToken(j JWTString) (*JWT, error)
+}
+
+type JWTVerifier struct {
+	Resolver  VerifierResolver
+	Validator *Validator
+}
+
+func (jv *JWTVerifier) VerifyToken(j JWTString) (*JWT, error) {
+	header, payload, _, err := verifyAndValidate(j, jv.Resolver, jv.Validator)
+	if err != nil {
+		return nil, err
+	}
+	return &JWT{Header: header, Payload: payload}, nil
+}
+
+// LegacyJWTVerifier verifies tokens generated by GenerateSignedJWTString, a nil Validator
+// validates them in the unit of their format, the legacy milliseconds or NumericDate seconds.
+type LegacyJWTVerifier struct {
+	ECDSA     *ECDSA
+	Validator *Validator
+}
+
+func (ljv *LegacyJWTVerifier) VerifyToken(j JWTString) (*JWT, error) {
+	ok, err := VerifyJWTString(ljv.ECDSA, j)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+
+	jwt, err := GetJWT(j)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := ljv.Validator
+	if validator == nil {
+		validator = &Validator{TimeUnit: jwt.Header.format().TimeUnit()}
+	}
+	err = validator.Validate(jwt.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt, nil
+}
@@ -0,0 +1,273 @@
+package restkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+const (
+	Authorization   = "Authorization"
+	WWWAuthenticate = "WWW-Authenticate"
+	DPoP            = "DPoP"
+)
+
+// accessTokenUse is the only token_use accepted, tokens without token_use are access tokens too.
+const accessTokenUse = "access"
+
+const (
+	BearerErrInvalidRequest    = "invalid_request"
+	BearerErrInvalidToken      = "invalid_token"
+	BearerErrInsufficientScope = "insufficient_scope"
+	DPoPErrInvalidProof        = "invalid_dpop_proof"
+)
+
+type authContextKey uint8
+
+const (
+	tokenContextKey authContextKey = iota
+	jwtContextKey
+	sessionContextKey
+)
+
+// BearerAuth authenticates requests with RFC 6750 bearer tokens, verified claims are stored in the
+// request context and read back with ClaimsFromContext.
+type BearerAuth struct {
+	Verifier jwtkit.TokenVerifier
+	Realm    string
+	// Optional lets requests without any token through unauthenticated, an invalid token is still refused
+	Optional bool
+	// CookieName is read when the request has no Authorization header
+	CookieName string
+	// DPoP accepts the DPoP scheme, tokens with cnf.jkt are then only accepted along a proof of their key
+	DPoP *jwtkit.DPoPVerifier
+	// RequireDPoP refuses the Bearer scheme and cookies, it needs DPoP
+	RequireDPoP bool
+	// RequestURL is the htu the proof must match, the default trusts r.Host and r.TLS so set it
+	// behind a proxy
+	RequestURL func(r *http.Request) string
+}
+
+func (ba *BearerAuth) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, err := ba.extractToken(r)
+		if err != nil {
+			ba.WriteError(w, http.StatusBadRequest, BearerErrInvalidRequest, err.Error(), "")
+			return
+		}
+		if token == "" {
+			if ba.Optional {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ba.WriteError(w, http.StatusUnauthorized, "", "", "")
+			return
+		}
+
+		jwt, err := ba.Verifier.VerifyToken(token)
+		if err != nil {
+			ba.writeSchemeError(w, scheme, http.StatusUnauthorized, BearerErrInvalidToken, bearerErrorDescription(err))
+			return
+		}
+		if jwt.Payload == nil || !isAccessToken(jwt.Payload) {
+			// refresh tokens and session cookies may share the key provider of access tokens
+			ba.writeSchemeError(w, scheme, http.StatusUnauthorized, BearerErrInvalidToken, "token is not an access token")
+			return
+		}
+
+		if scheme == DPoP {
+			proof, err := ba.verifyProof(r, token)
+			if err != nil {
+				ba.writeSchemeError(w, DPoP, http.StatusUnauthorized, DPoPErrInvalidProof, bearerErrorDescription(err))
+				return
+			}
+			err = proof.Confirms(jwt.Payload)
+			if err != nil {
+				ba.writeSchemeError(w, DPoP, http.StatusUnauthorized, BearerErrInvalidToken, err.Error())
+				return
+			}
+		} else if jwt.Payload.ConfirmationThumbprint() != "" {
+			// a bound token presented as bearer token is exactly what a stolen token looks like
+			ba.writeSchemeError(w, DPoP, http.StatusUnauthorized, BearerErrInvalidToken, "token is bound to a DPoP key")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithJWT(r.Context(), token, jwt)))
+	})
+}
+
+func isAccessToken(p *jwtkit.Payload) bool {
+	tokenUse, ok := p.Claims["token_use"]
+	return !ok || tokenUse == accessTokenUse
+}
+
+func (ba *BearerAuth) extractToken(r *http.Request) (string, jwtkit.JWTString, error) {
+	authorizations := r.Header.Values(Authorization)
+	if len(authorizations) > 1 {
+		return "", "", errors.New("multiple authorization headers")
+	}
+	if len(authorizations) == 1 {
+		scheme, token, ok := cutAuthorization(authorizations[0])
+		switch {
+		case ok && ba.DPoP != nil && strings.EqualFold(scheme, DPoP):
+			return DPoP, jwtkit.JWTString(token), nil
+		case ok && !ba.RequireDPoP && strings.EqualFold(scheme, "Bearer"):
+			return "Bearer", jwtkit.JWTString(token), nil
+		case ba.RequireDPoP:
+			return "", "", errors.New("authorization header is not a DPoP token")
+		case ba.DPoP != nil:
+			return "", "", errors.New("authorization header is not a bearer or DPoP token")
+		default:
+			return "", "", errors.New("authorization header is not a bearer token")
+		}
+	}
+
+	if ba.CookieName != "" && !ba.RequireDPoP {
+		cookie, err := r.Cookie(ba.CookieName)
+		if err == nil && cookie.Value != "" {
+			return "Bearer", jwtkit.JWTString(cookie.Value), nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// verifyProof verifies the single DPoP header of r against the request and the access token.
+func (ba *BearerAuth) verifyProof(r *http.Request, token jwtkit.JWTString) (*jwtkit.DPoPProof, error) {
+	proofs := r.Header.Values(DPoP)
+	if len(proofs) != 1 {
+		return nil, fmt.Errorf("%w: expected one DPoP header, got %d", jwtkit.ErrInvalidDPoPProof, len(proofs))
+	}
+
+	requestURL := ba.RequestURL
+	if requestURL == nil {
+		requestURL = defaultRequestURL
+	}
+
+	return ba.DPoP.Verify(jwtkit.JWTString(proofs[0]), r.Method, requestURL(r), token)
+}
+
+func defaultRequestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
+
+func cutAuthorization(authorization string) (scheme string, credentials string, ok bool) {
+	idx := strings.IndexByte(authorization, ' ')
+	if idx < 0 {
+		return "", "", false
+	}
+	credentials = strings.TrimSpace(authorization[idx+1:])
+	if credentials == "" {
+		return "", "", false
+	}
+	return authorization[:idx], credentials, true
+}
+
+// WriteError writes an RFC 6750 error response, errCode is empty when the request had no token at all.
+// With DPoP a challenge is written for every accepted scheme.
+func (ba *BearerAuth) WriteError(w http.ResponseWriter, statusCode int, errCode string, desc string, scope string) {
+	schemes := []string{"Bearer"}
+	if ba.RequireDPoP {
+		schemes = []string{DPoP}
+	} else if ba.DPoP != nil {
+		schemes = append(schemes, DPoP)
+	}
+	for _, scheme := range schemes {
+		w.Header().Add(WWWAuthenticate, ba.challenge(scheme, errCode, desc, scope))
+	}
+	w.WriteHeader(statusCode)
+}
+
+// writeSchemeError only challenges the scheme the request used.
+func (ba *BearerAuth) writeSchemeError(w http.ResponseWriter, scheme string, statusCode int, errCode string, desc string) {
+	w.Header().Set(WWWAuthenticate, ba.challenge(scheme, errCode, desc, ""))
+	w.WriteHeader(statusCode)
+}
+
+func (ba *BearerAuth) challenge(scheme string, errCode string, desc string, scope string) string {
+	params := []string{}
+	if ba.Realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", ba.Realm))
+	}
+	if errCode != "" {
+		params = append(params, fmt.Sprintf("error=%q", errCode))
+	}
+	if desc != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", sanitizeAuthParam(desc)))
+	}
+	if scope != "" {
+		params = append(params, fmt.Sprintf("scope=%q", sanitizeAuthParam(scope)))
+	}
+	if scheme == DPoP {
+		params = append(params, fmt.Sprintf("algs=%q", strings.Join(jwtkit.DPoPAlgorithms, " ")))
+	}
+
+	if len(params) == 0 {
+		return scheme
+	}
+	return scheme + " " + strings.Join(params, ", ")
+}
+
+// sanitizeAuthParam keeps only the characters RFC 6750 allows in error_description and scope.
+func sanitizeAuthParam(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func bearerErrorDescription(err error) string {
+	for _, knownErr := range []error{
+		jwtkit.ErrExpired,
+		jwtkit.ErrNotYetValid,
+		jwtkit.ErrRevoked,
+		jwtkit.ErrAudienceMismatch,
+		jwtkit.ErrIssuerMismatch,
+		jwtkit.ErrUnsupportedAlg,
+		jwtkit.ErrMalformed,
+		jwtkit.ErrBadEncoding,
+		jwtkit.ErrInvalidDPoPProof,
+		jwtkit.ErrDPoPReplayed,
+		jwtkit.ErrTokenTooOld,
+		jwtkit.ErrIssuedInFuture,
+		jwtkit.ErrInvalidSignature,
+	} {
+		if errors.Is(err, knownErr) {
+			return knownErr.Error()
+		}
+	}
+	return "token is invalid"
+}
+
+func ContextWithJWT(ctx context.Context, token jwtkit.JWTString, jwt *jwtkit.JWT) context.Context {
+	ctx = context.WithValue(ctx, tokenContextKey, token)
+	return context.WithValue(ctx, jwtContextKey, jwt)
+}
+
+func TokenFromContext(ctx context.Context) (jwtkit.JWTString, bool) {
+	token, ok := ctx.Value(tokenContextKey).(jwtkit.JWTString)
+	return token, ok
+}
+
+func JWTFromContext(ctx context.Context) (*jwtkit.JWT, bool) {
+	jwt, ok := ctx.Value(jwtContextKey).(*jwtkit.JWT)
+	return jwt, ok && jwt != nil
+}
+
+func ClaimsFromContext(ctx context.Context) (*jwtkit.Payload, bool) {
+	jwt, ok := JWTFromContext(ctx)
+	if !ok || jwt.Payload == nil {
+		return nil, false
+	}
+	return jwt.Payload, true
+}
@@ -0,0 +1,219 @@
+package restkit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+	"github.com/ilhammhdd/go-toolkit/restkit"
+)
+
+func newTestIssuer(t *testing.T) (*jwtkit.Issuer, *jwtkit.KeySet) {
+	ks := jwtkit.NewKeySet()
+	ks.Add("hmac", []byte("a secret that is long enough for HS256"))
+	ks.Activate("hmac")
+	signer, err := ks.Signer()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return &jwtkit.Issuer{Signer: signer, Issuer: "issuer", Expiration: 60000}, ks
+}
+
+func TestBearerAuth(t *testing.T) {
+	issuer, ks := newTestIssuer(t)
+	token, err := issuer.Issue("subject", map[string]interface{}{"role": "admin"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	expired, err := (&jwtkit.Issuer{Signer: issuer.Signer, Expiration: -60000}).Issue("subject", nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	access, err := issuer.Issue("subject", map[string]interface{}{"token_use": "access"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	session, err := issuer.Issue("subject", map[string]interface{}{"token_use": "session"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	protected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := restkit.ClaimsFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(claims.Subject))
+	})
+	bearerAuth := &restkit.BearerAuth{
+		Verifier:   &jwtkit.JWTVerifier{Resolver: ks},
+		Realm:      "api",
+		CookieName: "access_token",
+	}
+	optionalAuth := *bearerAuth
+	optionalAuth.Optional = true
+
+	testCases := []struct {
+		name            string
+		handler         http.Handler
+		authorization   string
+		cookie          string
+		expectedStatus  int
+		expectedBody    string
+		expectedWWWAuth string
+	}{
+		{"valid header", bearerAuth.Handler(protected), "Bearer " + string(token), "", http.StatusOK, "subject", ""},
+		{"valid cookie", bearerAuth.Handler(protected), "", string(token), http.StatusOK, "subject", ""},
+		{"missing", bearerAuth.Handler(protected), "", "", http.StatusUnauthorized, "", `Bearer realm="api"`},
+		{"expired", bearerAuth.Handler(protected), "Bearer " + string(expired), "", http.StatusUnauthorized, "", `Bearer realm="api", error="invalid_token", error_description="token is expired"`},
+		{"access token use", bearerAuth.Handler(protected), "Bearer " + string(access), "", http.StatusOK, "subject", ""},
+		{"session token use", bearerAuth.Handler(protected), "Bearer " + string(session), "", http.StatusUnauthorized, "", `Bearer realm="api", error="invalid_token", error_description="token is not an access token"`},
+		{"garbage", bearerAuth.Handler(protected), "Bearer abc", "", http.StatusUnauthorized, "", `Bearer realm="api", error="invalid_token", error_description="malformed token"`},
+		{"wrong scheme", bearerAuth.Handler(protected), "Basic abc", "", http.StatusBadRequest, "", `Bearer realm="api", error="invalid_request", error_description="authorization header is not a bearer token"`},
+		{"optional missing", optionalAuth.Handler(protected), "", "", http.StatusNoContent, "", ""},
+		{"optional invalid", optionalAuth.Handler(protected), "Bearer abc", "", http.StatusUnauthorized, "", `Bearer realm="api", error="invalid_token", error_description="malformed token"`},
+	}
+
+	for i := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if testCases[i].authorization != "" {
+			r.Header.Set(restkit.Authorization, testCases[i].authorization)
+		}
+		if testCases[i].cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: testCases[i].cookie})
+		}
+		w := httptest.NewRecorder()
+		testCases[i].handler.ServeHTTP(w, r)
+
+		if w.Code != testCases[i].expectedStatus || w.Body.String() != testCases[i].expectedBody || w.Header().Get(restkit.WWWAuthenticate) != testCases[i].expectedWWWAuth {
+			t.Fatalf("case: %s got: %d %q %q", testCases[i].name, w.Code, w.Body.String(), w.Header().Get(restkit.WWWAuthenticate))
+		}
+	}
+}
+
+func TestMethodRoutingPolicy(t *testing.T) {
+	issuer, ks := newTestIssuer(t)
+	token, err := issuer.Issue("42", map[string]interface{}{"scope": "users:read users:write", "roles": []string{"member"}})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	routing := &restkit.MethodRouting{
+		GetHandler:    ok,
+		PutHandler:    ok,
+		DeleteHandler: ok,
+		MethodsPolicy: map[string]restkit.Policy{
+			http.MethodGet: restkit.AllOf(
+				restkit.RequireScope("users:read"),
+				restkit.ClaimEqualsPathParam("sub", "userID", restkit.PathPattern("/users/{userID}")),
+			),
+			http.MethodPut:    restkit.RequireScope("users:admin"),
+			http.MethodDelete: restkit.AnyRole("admin", "owner"),
+		},
+	}
+	handler := (&restkit.BearerAuth{Verifier: &jwtkit.JWTVerifier{Resolver: ks}}).Handler(routing)
+
+	testCases := []struct {
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{http.MethodGet, "/users/42", http.StatusOK},
+		{http.MethodGet, "/users/43", http.StatusForbidden},
+		{http.MethodPut, "/users/42", http.StatusForbidden},
+		{http.MethodDelete, "/users/42", http.StatusForbidden},
+	}
+
+	for i := range testCases {
+		r := httptest.NewRequest(testCases[i].method, testCases[i].path, nil)
+		r.Header.Set(restkit.Authorization, "Bearer "+string(token))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != testCases[i].expectedStatus {
+			t.Fatalf("method: %s path: %s expected: %d got: %d %s", testCases[i].method, testCases[i].path, testCases[i].expectedStatus, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusForbidden && w.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("expected detailed error body got: %s", w.Body.String())
+		}
+	}
+}
+
+func TestBearerAuthDPoP(t *testing.T) {
+	issuer, ks := newTestIssuer(t)
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	prover, err := jwtkit.NewDPoPProver(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	jkt, err := prover.JWK.Thumbprint()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	bound, err := issuer.Issue("subject", jwtkit.WithConfirmation(nil, jkt))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	unbound, err := issuer.Issue("subject", nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	proof := func(method string, token jwtkit.JWTString) string {
+		p, err := prover.Proof(method, "http://example.com/resource", token)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		return string(p)
+	}
+	replayed := proof(http.MethodGet, bound)
+
+	protected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	handler := (&restkit.BearerAuth{
+		Verifier: &jwtkit.JWTVerifier{Resolver: ks},
+		DPoP:     &jwtkit.DPoPVerifier{Replay: jwtkit.NewMemoryReplayCache()},
+	}).Handler(protected)
+	algs := `algs="` + strings.Join(jwtkit.DPoPAlgorithms, " ") + `"`
+
+	testCases := []struct {
+		name            string
+		authorization   string
+		proof           string
+		expectedStatus  int
+		expectedWWWAuth string
+	}{
+		{"bound", "DPoP " + string(bound), replayed, http.StatusNoContent, ""},
+		{"replayed proof", "DPoP " + string(bound), replayed, http.StatusUnauthorized, `DPoP error="invalid_dpop_proof", error_description="DPoP proof is replayed", ` + algs},
+		{"missing proof", "DPoP " + string(bound), "", http.StatusUnauthorized, `DPoP error="invalid_dpop_proof", error_description="invalid DPoP proof", ` + algs},
+		{"proof of other method", "DPoP " + string(bound), proof(http.MethodPost, bound), http.StatusUnauthorized, `DPoP error="invalid_dpop_proof", error_description="invalid DPoP proof", ` + algs},
+		{"unbound token", "DPoP " + string(unbound), proof(http.MethodGet, unbound), http.StatusUnauthorized, `DPoP error="invalid_token", error_description="token is not bound to the DPoP key", ` + algs},
+		{"bound token as bearer", "Bearer " + string(bound), "", http.StatusUnauthorized, `DPoP error="invalid_token", error_description="token is bound to a DPoP key", ` + algs},
+		{"unbound token as bearer", "Bearer " + string(unbound), "", http.StatusNoContent, ""},
+		{"missing", "", "", http.StatusUnauthorized, "Bearer"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/resource?page=2", nil)
+		if tc.authorization != "" {
+			r.Header.Set(restkit.Authorization, tc.authorization)
+		}
+		if tc.proof != "" {
+			r.Header.Set(restkit.DPoP, tc.proof)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedStatus || w.Header().Get(restkit.WWWAuthenticate) != tc.expectedWWWAuth {
+			t.Fatalf("case: %s got: %d %q", tc.name, w.Code, w.Header().Get(restkit.WWWAuthenticate))
+		}
+	}
+}
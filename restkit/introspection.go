@@ -0,0 +1,214 @@
+package restkit
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+const (
+	OAuthErrInvalidRequest       = "invalid_request"
+	OAuthErrInvalidClient        = "invalid_client"
+	OAuthErrUnauthorizedClient   = "unauthorized_client"
+	OAuthErrUnsupportedTokenType = "unsupported_token_type"
+	OAuthErrServerError          = "server_error"
+)
+
+// maxFormSize bounds the form of introspection and revocation requests, tokens are far smaller.
+const maxFormSize = 64 * 1024
+
+// ClientAuthenticator checks the client credentials protecting the introspection and revocation endpoints.
+type ClientAuthenticator interface {
+	AuthenticateClient(clientID string, clientSecret string) (bool, error)
+}
+
+// ClientSecrets maps client id to client secret, secrets are compared in constant time.
+type ClientSecrets map[string]string
+
+func (cs ClientSecrets) AuthenticateClient(clientID string, clientSecret string) (bool, error) {
+	secret, ok := cs[clientID]
+	// hashing first keeps the comparison constant time whatever the lengths are
+	expected := sha256.Sum256([]byte(secret))
+	given := sha256.Sum256([]byte(clientSecret))
+	return subtle.ConstantTimeCompare(expected[:], given[:]) == 1 && ok, nil
+}
+
+// IntrospectionHandler implements RFC 7662, any error of Verifier answers {"active":false}. Times are
+// answered in seconds as RFC 7662 requires, TimeUnit is the unit of the verified tokens. Refresh
+// tokens are only introspected when the Validator of Verifier has AllowRefreshTokens set.
+type IntrospectionHandler struct {
+	Verifier jwtkit.TokenVerifier
+	Clients  ClientAuthenticator
+	Realm    string
+	TimeUnit jwtkit.TimeUnit
+}
+
+func (ih *IntrospectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, _, ok := readTokenForm(w, r, ih.Clients, ih.Realm)
+	if !ok {
+		return
+	}
+
+	jwt, err := ih.Verifier.VerifyToken(token)
+	if err != nil || jwt.Payload == nil {
+		writeOAuthJSON(w, http.StatusOK, map[string]interface{}{"active": false})
+		return
+	}
+
+	response := jwt.Payload.FlatClaims()
+	for _, name := range []string{"iat", "exp", "nbf"} {
+		if timestamp, ok := response[name].(int64); ok {
+			response[name] = jwtkit.Seconds.Timestamp(ih.TimeUnit.Time(timestamp))
+		}
+	}
+	response["active"] = true
+	if jwt.Payload.IsRefreshToken() {
+		response["token_type"] = "refresh_token"
+	} else {
+		response["token_type"] = "access_token"
+	}
+
+	writeOAuthJSON(w, http.StatusOK, response)
+}
+
+// RevocationHandler implements RFC 7009. Access tokens are revoked in Store until their exp, refresh
+// tokens revoke their whole family through Refresh. Tokens that do not verify are answered with 200
+// as RFC 7009 requires, token_type_hint is not needed as the token itself tells its type. The
+// Validator of Verifier needs AllowRefreshTokens for refresh tokens to be revoked. A client only
+// revokes tokens issued to it, told by their client_id, azp or else aud claim.
+type RevocationHandler struct {
+	Verifier jwtkit.TokenVerifier
+	// Store is needed to revoke access tokens, without it they are answered unsupported_token_type
+	Store   jwtkit.RevocationStore
+	Refresh *jwtkit.RefreshIssuer
+	Clients ClientAuthenticator
+	Realm   string
+}
+
+func (rh *RevocationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, clientID, ok := readTokenForm(w, r, rh.Clients, rh.Realm)
+	if !ok {
+		return
+	}
+
+	jwt, err := rh.Verifier.VerifyToken(token)
+	if err != nil || jwt.Payload == nil {
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !issuedTo(jwt.Payload, clientID) {
+		writeOAuthError(w, http.StatusBadRequest, OAuthErrUnauthorizedClient, "token was not issued to the client")
+		return
+	}
+
+	switch {
+	case jwt.Payload.IsRefreshToken() && rh.Refresh != nil:
+		err = rh.Refresh.RevokeFamily(token)
+	case jwt.Payload.IsRefreshToken() || jwt.Payload.Id == "" || rh.Store == nil:
+		writeOAuthError(w, http.StatusBadRequest, OAuthErrUnsupportedTokenType, "token can not be revoked")
+		return
+	default:
+		err = rh.Store.Revoke(jwt.Payload.Id, jwt.Payload.ExpiredAt)
+	}
+	if err != nil {
+		writeOAuthError(w, http.StatusServiceUnavailable, OAuthErrServerError, "revocation failed")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// issuedTo tells whether the token names clientID in client_id, or in azp, or else in aud.
+func issuedTo(p *jwtkit.Payload, clientID string) bool {
+	for _, claim := range []string{"client_id", "azp"} {
+		if value, ok := p.Claims[claim].(string); ok {
+			return value == clientID
+		}
+	}
+	return p.Audience == clientID || p.Audiences.Contains(clientID)
+}
+
+// readTokenForm authenticates the client and reads the token parameter of a form encoded POST, it
+// writes the error response itself when ok is false.
+func readTokenForm(w http.ResponseWriter, r *http.Request, clients ClientAuthenticator, realm string) (token jwtkit.JWTString, clientID string, ok bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return "", "", false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, OAuthErrInvalidRequest, "invalid form")
+		return "", "", false
+	}
+
+	clientID, clientSecret, err := clientCredentials(r)
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, OAuthErrInvalidRequest, err.Error())
+		return "", "", false
+	}
+	authenticated := false
+	if clientID != "" {
+		authenticated, err = clients.AuthenticateClient(clientID, clientSecret)
+		if err != nil {
+			writeOAuthError(w, http.StatusServiceUnavailable, OAuthErrServerError, "client authentication failed")
+			return "", "", false
+		}
+	}
+	if !authenticated {
+		w.Header().Set(WWWAuthenticate, fmt.Sprintf("Basic realm=%q", sanitizeAuthParam(realm)))
+		writeOAuthError(w, http.StatusUnauthorized, OAuthErrInvalidClient, "client authentication failed")
+		return "", "", false
+	}
+
+	token = jwtkit.JWTString(r.PostForm.Get("token"))
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, OAuthErrInvalidRequest, "missing token")
+		return "", "", false
+	}
+
+	return token, clientID, true
+}
+
+// clientCredentials reads HTTP Basic or client_id and client_secret form parameters, RFC 6749 refuses
+// a request using both. Basic credentials are form encoded before they are base64 encoded.
+func clientCredentials(r *http.Request) (string, string, error) {
+	basicID, basicSecret, hasBasic := r.BasicAuth()
+	formID := r.PostForm.Get("client_id")
+	if hasBasic && formID != "" {
+		return "", "", errors.New("multiple client authentication methods")
+	}
+	if !hasBasic {
+		return formID, r.PostForm.Get("client_secret"), nil
+	}
+
+	clientID, err := url.QueryUnescape(basicID)
+	if err != nil {
+		return "", "", errors.New("invalid client id encoding")
+	}
+	clientSecret, err := url.QueryUnescape(basicSecret)
+	if err != nil {
+		return "", "", errors.New("invalid client secret encoding")
+	}
+	return clientID, clientSecret, nil
+}
+
+func writeOAuthError(w http.ResponseWriter, statusCode int, errCode string, desc string) {
+	writeOAuthJSON(w, statusCode, map[string]interface{}{"error": errCode, "error_description": desc})
+}
+
+func writeOAuthJSON(w http.ResponseWriter, statusCode int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,114 @@
+package restkit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+	"github.com/ilhammhdd/go-toolkit/restkit"
+)
+
+func postForm(handler http.Handler, form url.Values, clientID string, clientSecret string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		r.SetBasicAuth(clientID, clientSecret)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIntrospectionAndRevocation(t *testing.T) {
+	issuer, ks := newTestIssuer(t)
+	refreshIssuer := &jwtkit.RefreshIssuer{Access: issuer, RefreshExpiration: 120000, Resolver: ks, Store: jwtkit.NewMemoryRefreshStore()}
+	pair, err := refreshIssuer.IssuePair("subject", map[string]interface{}{"scope": "users:read", "client_id": "gateway"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	revocations := jwtkit.NewMemoryRevocationStore()
+	verifier := &jwtkit.JWTVerifier{Resolver: ks, Validator: &jwtkit.Validator{Revocations: revocations, AllowRefreshTokens: true}}
+	clients := restkit.ClientSecrets{"gateway": "gateway secret", "other": "other secret"}
+	unbound, err := issuer.Issue("subject", nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	withoutStore := &restkit.RevocationHandler{Verifier: verifier, Clients: clients}
+	introspection := &restkit.IntrospectionHandler{Verifier: verifier, Clients: clients, Realm: "introspection"}
+	revocation := &restkit.RevocationHandler{Verifier: verifier, Store: revocations, Refresh: refreshIssuer, Clients: clients}
+
+	introspect := func(token jwtkit.JWTString) map[string]interface{} {
+		w := postForm(introspection, url.Values{"token": {string(token)}}, "gateway", "gateway secret")
+		var response map[string]interface{}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &response) != nil {
+			t.Fatalf("introspection got: %d %s", w.Code, w.Body.String())
+		}
+		return response
+	}
+
+	response := introspect(pair.AccessToken)
+	if response["active"] != true || response["sub"] != "subject" || response["scope"] != "users:read" || response["token_type"] != "access_token" {
+		t.Fatalf("unexpected introspection response got: %v", response)
+	}
+	jwt, err := jwtkit.GetJWT(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if response["exp"] != float64(jwt.Payload.ExpiredAt) {
+		t.Fatalf("expected exp in seconds got: %v", response["exp"])
+	}
+	if response = introspect("garbage"); len(response) != 1 || response["active"] != false {
+		t.Fatalf("expected inactive got: %v", response)
+	}
+
+	testCases := []struct {
+		name           string
+		handler        http.Handler
+		form           url.Values
+		clientID       string
+		clientSecret   string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"wrong secret", introspection, url.Values{"token": {string(pair.AccessToken)}}, "gateway", "wrong", http.StatusUnauthorized, restkit.OAuthErrInvalidClient},
+		{"unknown client", revocation, url.Values{"token": {string(pair.AccessToken)}}, "unknown", "gateway secret", http.StatusUnauthorized, restkit.OAuthErrInvalidClient},
+		{"no client", introspection, url.Values{"token": {string(pair.AccessToken)}}, "", "", http.StatusUnauthorized, restkit.OAuthErrInvalidClient},
+		{"form client", introspection, url.Values{"token": {string(pair.AccessToken)}, "client_id": {"gateway"}, "client_secret": {"gateway secret"}}, "", "", http.StatusOK, ""},
+		{"both client methods", introspection, url.Values{"token": {string(pair.AccessToken)}, "client_id": {"gateway"}}, "gateway", "gateway secret", http.StatusBadRequest, restkit.OAuthErrInvalidRequest},
+		{"missing token", revocation, url.Values{}, "gateway", "gateway secret", http.StatusBadRequest, restkit.OAuthErrInvalidRequest},
+		{"revoke invalid token", revocation, url.Values{"token": {"garbage"}}, "gateway", "gateway secret", http.StatusOK, ""},
+		{"revoke access token of another client", revocation, url.Values{"token": {string(pair.AccessToken)}}, "other", "other secret", http.StatusBadRequest, restkit.OAuthErrUnauthorizedClient},
+		{"revoke refresh token of another client", revocation, url.Values{"token": {string(pair.RefreshToken)}}, "other", "other secret", http.StatusBadRequest, restkit.OAuthErrUnauthorizedClient},
+		{"revoke token of no client", revocation, url.Values{"token": {string(unbound)}}, "gateway", "gateway secret", http.StatusBadRequest, restkit.OAuthErrUnauthorizedClient},
+		{"revoke access token without store", withoutStore, url.Values{"token": {string(pair.AccessToken)}}, "gateway", "gateway secret", http.StatusBadRequest, restkit.OAuthErrUnsupportedTokenType},
+		{"revoke access token", revocation, url.Values{"token": {string(pair.AccessToken)}, "token_type_hint": {"access_token"}}, "gateway", "gateway secret", http.StatusOK, ""},
+		{"revoke refresh token", revocation, url.Values{"token": {string(pair.RefreshToken)}}, "gateway", "gateway secret", http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		w := postForm(tc.handler, tc.form, tc.clientID, tc.clientSecret)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != tc.expectedStatus || (tc.expectedError != "" && response["error"] != tc.expectedError) {
+			t.Fatalf("case: %s got: %d %s", tc.name, w.Code, w.Body.String())
+		}
+	}
+
+	if response = introspect(pair.AccessToken); response["active"] != false {
+		t.Fatalf("expected revoked access token to be inactive got: %v", response)
+	}
+	if _, err = refreshIssuer.Refresh(pair.RefreshToken); err != jwtkit.ErrRefreshFamilyRevoked {
+		t.Fatalf("expected %v got: %v", jwtkit.ErrRefreshFamilyRevoked, err)
+	}
+
+	w := httptest.NewRecorder()
+	introspection.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token="+string(pair.AccessToken), nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected %d got: %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
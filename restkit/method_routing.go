@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/ilhammhdd/go-toolkit/errorkit"
 )
 
 type CORSHandler interface {
@@ -21,35 +23,38 @@ type MethodRouting struct {
 	TraceHandler            http.Handler
 	OptionsHandler          http.Handler
 	MethodsCORSHeaderPolicy *MethodsCORSHeaderPolicy
-	corsHeaderPolicy        *CORSHeaderPolicy
+	// MethodsPolicy authorizes the claims BearerAuth stored in the request context, per http method
+	MethodsPolicy          map[string]Policy
+	PolicyErrDescGenerator errorkit.ErrDescGenerator
+	corsHeaderPolicy       *CORSHeaderPolicy
 }
 
 func (mr *MethodRouting) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == http.MethodPost && mr.PostHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodPost, &w)
-		mr.PostHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.PostHandler, w, r)
 	case r.Method == http.MethodPut && mr.PutHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodPut, &w)
-		mr.PutHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.PutHandler, w, r)
 	case r.Method == http.MethodDelete && mr.DeleteHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodDelete, &w)
-		mr.DeleteHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.DeleteHandler, w, r)
 	case r.Method == http.MethodGet && mr.GetHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodGet, &w)
-		mr.GetHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.GetHandler, w, r)
 	case r.Method == http.MethodPatch && mr.PatchHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodPatch, &w)
-		mr.PatchHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.PatchHandler, w, r)
 	case r.Method == http.MethodConnect && mr.ConnectHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodConnect, &w)
-		mr.ConnectHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.ConnectHandler, w, r)
 	case r.Method == http.MethodHead && mr.HeadHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodHead, &w)
-		mr.HeadHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.HeadHandler, w, r)
 	case r.Method == http.MethodTrace && mr.TraceHandler != nil:
 		mr.setCorsResponseHeaderIfExists(http.MethodTrace, &w)
-		mr.TraceHandler.ServeHTTP(w, r)
+		mr.serveAuthorized(mr.TraceHandler, w, r)
 	case r.Method == http.MethodOptions && mr.MethodsCORSHeaderPolicy != nil:
 		corsResponseHeader, statusCode := (*mr.MethodsCORSHeaderPolicy).Validate(r)
 		if corsResponseHeader == nil && statusCode < 200 || statusCode > 300 {
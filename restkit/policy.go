@@ -0,0 +1,237 @@
+package restkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ilhammhdd/go-toolkit/errorkit"
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+const callTraceFilePolicy = "/restkit/policy.go"
+
+const (
+	ScopeClaim = "scope"
+	RolesClaim = "roles"
+)
+
+// PolicyError is returned by policies, it carries the errorkit description constant and its args
+// so the response can be described by whichever ErrDescGenerator the routing uses.
+type PolicyError struct {
+	CallTrace    string
+	ErrDescConst uint
+	Args         []string
+}
+
+func (pe *PolicyError) Error() string {
+	return fmt.Sprintf("call_trace: %s err_desc_const: %d args: %s", pe.CallTrace, pe.ErrDescConst, strings.Join(pe.Args, ","))
+}
+
+// Policy authorizes an already authenticated request, claims is never nil.
+type Policy interface {
+	Authorize(r *http.Request, claims *jwtkit.Payload) error
+}
+
+type PolicyFunc func(r *http.Request, claims *jwtkit.Payload) error
+
+func (pf PolicyFunc) Authorize(r *http.Request, claims *jwtkit.Payload) error {
+	return pf(r, claims)
+}
+
+type PathParamFunc func(r *http.Request, name string) string
+
+var DefaultPolicyErrDescGenerator errorkit.ErrDescGenerator = errorkit.ErrDescGeneratorFunc(func(errDescConst uint, args ...string) string {
+	switch errDescConst {
+	case errorkit.FlowErrUnauthenticated:
+		return "authentication required"
+	case errorkit.FlowErrInsufficientScope:
+		return fmt.Sprintf("insufficient scope, required: %s", strings.Join(args, " "))
+	case errorkit.FlowErrInsufficientRole:
+		return fmt.Sprintf("insufficient role, required: %s", strings.Join(args, " "))
+	case errorkit.FlowErrClaimMismatch:
+		return fmt.Sprintf("claim %s does not match", strings.Join(args, " "))
+	default:
+		return "forbidden"
+	}
+})
+
+// RequireScope requires every scope in the space delimited scope claim.
+func RequireScope(scopes ...string) Policy {
+	return PolicyFunc(func(r *http.Request, claims *jwtkit.Payload) error {
+		granted := claimStrings(claims, ScopeClaim)
+		for _, scope := range scopes {
+			if !containsString(granted, scope) {
+				return &PolicyError{fmt.Sprintf("%s#RequireScope", callTraceFilePolicy), errorkit.FlowErrInsufficientScope, scopes}
+			}
+		}
+		return nil
+	})
+}
+
+func AnyRole(roles ...string) Policy {
+	return PolicyFunc(func(r *http.Request, claims *jwtkit.Payload) error {
+		granted := claimStrings(claims, RolesClaim)
+		for _, role := range roles {
+			if containsString(granted, role) {
+				return nil
+			}
+		}
+		return &PolicyError{fmt.Sprintf("%s#AnyRole", callTraceFilePolicy), errorkit.FlowErrInsufficientRole, roles}
+	})
+}
+
+func AllRoles(roles ...string) Policy {
+	return PolicyFunc(func(r *http.Request, claims *jwtkit.Payload) error {
+		granted := claimStrings(claims, RolesClaim)
+		for _, role := range roles {
+			if !containsString(granted, role) {
+				return &PolicyError{fmt.Sprintf("%s#AllRoles", callTraceFilePolicy), errorkit.FlowErrInsufficientRole, roles}
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimEqualsPathParam requires the claim to equal the path param, e.g. sub must equal {userID}.
+func ClaimEqualsPathParam(claim string, param string, pathParam PathParamFunc) Policy {
+	return PolicyFunc(func(r *http.Request, claims *jwtkit.Payload) error {
+		paramValue := pathParam(r, param)
+		claimValue, ok := claimString(claims, claim)
+		if paramValue == "" || !ok || claimValue != paramValue {
+			return &PolicyError{fmt.Sprintf("%s#ClaimEqualsPathParam", callTraceFilePolicy), errorkit.FlowErrClaimMismatch, []string{claim, param}}
+		}
+		return nil
+	})
+}
+
+func AllOf(policies ...Policy) Policy {
+	return PolicyFunc(func(r *http.Request, claims *jwtkit.Payload) error {
+		for _, policy := range policies {
+			if err := policy.Authorize(r, claims); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PathPattern extracts {name} segments of pattern from the request path, e.g. "/users/{userID}".
+func PathPattern(pattern string) PathParamFunc {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	return func(r *http.Request, name string) string {
+		pathSegments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(pathSegments) != len(patternSegments) {
+			return ""
+		}
+		var value string
+		for i := range patternSegments {
+			if patternSegments[i] == "{"+name+"}" {
+				value = pathSegments[i]
+			} else if !strings.HasPrefix(patternSegments[i], "{") && patternSegments[i] != pathSegments[i] {
+				return ""
+			}
+		}
+		return value
+	}
+}
+
+func claimString(claims *jwtkit.Payload, claim string) (string, bool) {
+	switch claim {
+	case "sub":
+		return claims.Subject, claims.Subject != ""
+	case "iss":
+		return claims.Issuer, claims.Issuer != ""
+	case "jti":
+		return claims.Id, claims.Id != ""
+	}
+	value, ok := claims.Claims[claim]
+	if !ok || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64, bool, json.Number:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// claimStrings reads space delimited string claims as well as array claims.
+func claimStrings(claims *jwtkit.Payload, claim string) []string {
+	switch v := claims.Claims[claim].(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for i := range v {
+			if str, ok := v[i].(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+func containsString(strs []string, str string) bool {
+	for i := range strs {
+		if strs[i] == str {
+			return true
+		}
+	}
+	return false
+}
+
+func (mr *MethodRouting) serveAuthorized(handler http.Handler, w http.ResponseWriter, r *http.Request) {
+	policy, ok := mr.MethodsPolicy[r.Method]
+	if !ok || policy == nil {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	descGenerator := mr.PolicyErrDescGenerator
+	if descGenerator == nil {
+		descGenerator = DefaultPolicyErrDescGenerator
+	}
+	callTraceFunc := fmt.Sprintf("%s#MethodRouting.serveAuthorized", callTraceFilePolicy)
+
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		w.Header().Set(WWWAuthenticate, "Bearer")
+		writeDetailedError(w, http.StatusUnauthorized, errorkit.NewDetailedError(true, callTraceFunc, nil, errorkit.FlowErrUnauthenticated, descGenerator))
+		return
+	}
+
+	err := policy.Authorize(r, claims)
+	if err == nil {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	policyErr, ok := err.(*PolicyError)
+	if !ok {
+		policyErr = &PolicyError{CallTrace: callTraceFunc, ErrDescConst: errorkit.FlowErrForbidden}
+	}
+	if policyErr.ErrDescConst == errorkit.FlowErrInsufficientScope {
+		w.Header().Set(WWWAuthenticate, fmt.Sprintf("Bearer error=%q, scope=%q", BearerErrInsufficientScope, sanitizeAuthParam(strings.Join(policyErr.Args, " "))))
+	}
+	writeDetailedError(w, http.StatusForbidden, errorkit.NewDetailedError(true, policyErr.CallTrace, err, policyErr.ErrDescConst, descGenerator, policyErr.Args...))
+}
+
+func writeDetailedError(w http.ResponseWriter, statusCode int, detailedErr *errorkit.DetailedError) {
+	body, err := json.Marshal(detailedErr)
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
@@ -0,0 +1,338 @@
+package restkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+)
+
+const (
+	DefaultSessionCookieName  = "session"
+	DefaultSessionIdleTimeout = 30 * time.Minute
+	// DefaultMaxCookieSize leaves room for the attributes within the 4096 bytes browsers keep per cookie
+	DefaultMaxCookieSize = 3800
+	DefaultMaxChunks     = 4
+)
+
+const (
+	sessionTokenUse    = "session"
+	sessionValuesClaim = "data"
+)
+
+var (
+	ErrNoSession       = errors.New("no session")
+	ErrSessionTooLarge = errors.New("session does not fit in the cookies")
+)
+
+// Session is the data of one cookie session, Id and the times are set by SessionManager.Save.
+type Session struct {
+	Id     string
+	Values map[string]interface{}
+	// IssuedAt is when the session started and ExpiredAt when it ends unless it is used, both in seconds
+	IssuedAt  int64
+	ExpiredAt int64
+}
+
+// SessionManager keeps sessions in signed cookies, and in encrypted cookies when EncryptionKeys is
+// set. After rotating a key move the old one to PreviousKeys or PreviousEncryptionKeys, sessions
+// written with it stay readable and are rewritten with the new key the next time they are saved.
+type SessionManager struct {
+	Keys         jwtkit.KeyProvider
+	PreviousKeys jwtkit.KeyProvider
+	// EncryptionKeys must hold an ECDSA key, sessions are encrypted with ECDH-ES and A256GCM
+	EncryptionKeys         jwtkit.KeyProvider
+	PreviousEncryptionKeys jwtkit.KeyProvider
+
+	CookieName string
+	Path       string
+	Domain     string
+	// Insecure drops the Secure attribute, only ever for local development over plain HTTP
+	Insecure bool
+	// SameSite defaults to Lax, HttpOnly is always set
+	SameSite http.SameSite
+	// MaxCookieSize bounds name and value of one cookie, larger sessions are split across MaxChunks cookies
+	MaxCookieSize int
+	MaxChunks     int
+
+	// IdleTimeout slides, every save extends the session by it. MaxLifetime bounds the session
+	// from its start whatever its use, zero means no bound.
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
+	Clock       jwtkit.Clock
+}
+
+func NewSession() *Session {
+	return &Session{Values: make(map[string]interface{})}
+}
+
+// Load reads the session of r, ErrNoSession when r has no session cookie.
+func (sm *SessionManager) Load(r *http.Request) (*Session, error) {
+	value := sm.readChunks(r)
+	if value == "" {
+		return nil, ErrNoSession
+	}
+	token := jwtkit.JWTString(value)
+
+	var err error
+	if sm.EncryptionKeys != nil {
+		token, err = sm.decrypt(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jwt, err := sm.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	tokenUse, _ := jwt.Payload.Claims["token_use"].(string)
+	if tokenUse != sessionTokenUse {
+		return nil, fmt.Errorf("%w: not a session token", jwtkit.ErrMalformed)
+	}
+	values, _ := jwt.Payload.Claims[sessionValuesClaim].(map[string]interface{})
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	return &Session{
+		Id:        jwt.Payload.Id,
+		Values:    values,
+		IssuedAt:  jwt.Payload.IssuedAt,
+		ExpiredAt: jwt.Payload.ExpiredAt,
+	}, nil
+}
+
+// Save writes s into the cookies of w and extends it by IdleTimeout, r is needed to expire chunks
+// a previous larger session left behind.
+func (sm *SessionManager) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	signer, err := jwtkit.ProviderSigner(sm.Keys, "")
+	if err != nil {
+		return err
+	}
+	issuer := &jwtkit.Issuer{
+		Signer:     signer,
+		Expiration: jwtkit.JWTExpiration(sm.idleTimeout().Milliseconds()),
+		Clock:      sm.Clock,
+		TimeUnit:   jwtkit.Seconds,
+	}
+	rc := &jwtkit.RegisteredClaims{Id: s.Id, IssuedAt: s.IssuedAt}
+	token, err := issuer.IssueRegistered(rc, map[string]interface{}{
+		"token_use":        sessionTokenUse,
+		sessionValuesClaim: s.Values,
+	})
+	if err != nil {
+		return err
+	}
+
+	if sm.EncryptionKeys != nil {
+		encrypter, err := jwtkit.NewEncrypter(jwtkit.KeyAlgorithmECDHES, sm.EncryptionKeys)
+		if err != nil {
+			return err
+		}
+		token, err = encrypter.Encrypt([]byte(token), jwtkit.ContentTypeJWT)
+		if err != nil {
+			return err
+		}
+	}
+
+	chunks, err := sm.chunk(string(token))
+	if err != nil {
+		return err
+	}
+	for i, chunk := range chunks {
+		http.SetCookie(w, sm.cookie(sm.chunkName(i), chunk, rc.ExpiredAt))
+	}
+	sm.expireChunks(w, r, len(chunks))
+
+	s.Id, s.IssuedAt, s.ExpiredAt = rc.Id, rc.IssuedAt, rc.ExpiredAt
+
+	return nil
+}
+
+// Destroy expires every cookie of the session.
+func (sm *SessionManager) Destroy(w http.ResponseWriter, r *http.Request) {
+	sm.expireChunks(w, r, 0)
+}
+
+// Handler puts the session, or a new empty one, into the request context for SessionFromContext.
+// A session past half of its IdleTimeout is saved before next runs so it slides without every
+// request rewriting the cookie, handlers that change the session must Save it themselves.
+func (sm *SessionManager) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := sm.Load(r)
+		if err != nil {
+			if !errors.Is(err, ErrNoSession) {
+				sm.Destroy(w, r)
+			}
+			session = NewSession()
+		} else if remaining := jwtkit.Seconds.Time(session.ExpiredAt).Sub(sm.now()); remaining < sm.idleTimeout()/2 {
+			err = sm.Save(w, r, session)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithSession(r.Context(), session)))
+	})
+}
+
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey, session)
+}
+
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionContextKey).(*Session)
+	return session, ok && session != nil
+}
+
+func (sm *SessionManager) decrypt(token jwtkit.JWTString) (jwtkit.JWTString, error) {
+	var err error
+	for _, keys := range []jwtkit.KeyProvider{sm.EncryptionKeys, sm.PreviousEncryptionKeys} {
+		if keys == nil {
+			continue
+		}
+		var header *jwtkit.JWEHeader
+		var plaintext []byte
+		header, plaintext, err = (&jwtkit.Decrypter{Keys: keys}).Decrypt(token)
+		if err == nil {
+			if header.ContentType != jwtkit.ContentTypeJWT {
+				return "", fmt.Errorf("%w: not a nested JWT", jwtkit.ErrMalformed)
+			}
+			return jwtkit.JWTString(plaintext), nil
+		}
+	}
+	return "", err
+}
+
+func (sm *SessionManager) verify(token jwtkit.JWTString) (*jwtkit.JWT, error) {
+	validator := &jwtkit.Validator{
+		Clock:             sm.Clock,
+		TimeUnit:          jwtkit.Seconds,
+		MaxAge:            sm.MaxLifetime,
+		RequireExpiration: true,
+	}
+	var err error
+	for _, keys := range []jwtkit.KeyProvider{sm.Keys, sm.PreviousKeys} {
+		if keys == nil {
+			continue
+		}
+		var jwt *jwtkit.JWT
+		jwt, err = (&jwtkit.JWTVerifier{Resolver: jwtkit.ProviderVerifierResolver(keys), Validator: validator}).VerifyToken(token)
+		if err == nil {
+			return jwt, nil
+		}
+	}
+	return nil, err
+}
+
+// readChunks joins the session cookie with its numbered chunks, stopping at the first missing one.
+func (sm *SessionManager) readChunks(r *http.Request) string {
+	value := ""
+	for i := 0; i < sm.maxChunks(); i++ {
+		cookie, err := r.Cookie(sm.chunkName(i))
+		if err != nil {
+			break
+		}
+		value += cookie.Value
+	}
+	return value
+}
+
+func (sm *SessionManager) chunk(value string) ([]string, error) {
+	chunks := []string{}
+	for i := 0; len(value) != 0; i++ {
+		if i == sm.maxChunks() {
+			return nil, ErrSessionTooLarge
+		}
+		size := sm.maxCookieSize() - len(sm.chunkName(i)) - 1
+		if size <= 0 {
+			return nil, ErrSessionTooLarge
+		}
+		if size > len(value) {
+			size = len(value)
+		}
+		chunks = append(chunks, value[:size])
+		value = value[size:]
+	}
+	return chunks, nil
+}
+
+// expireChunks expires the chunks from index from on that r carries.
+func (sm *SessionManager) expireChunks(w http.ResponseWriter, r *http.Request, from int) {
+	for i := from; i < sm.maxChunks(); i++ {
+		if _, err := r.Cookie(sm.chunkName(i)); err != nil {
+			continue
+		}
+		cookie := sm.cookie(sm.chunkName(i), "", 0)
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+func (sm *SessionManager) cookie(name string, value string, expiredAt int64) *http.Cookie {
+	sameSite := sm.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+	path := sm.Path
+	if path == "" {
+		path = "/"
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   sm.Domain,
+		Secure:   !sm.Insecure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+	if expiredAt != 0 {
+		cookie.Expires = jwtkit.Seconds.Time(expiredAt)
+	}
+	return cookie
+}
+
+func (sm *SessionManager) chunkName(i int) string {
+	name := sm.CookieName
+	if name == "" {
+		name = DefaultSessionCookieName
+	}
+	if i == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s_%d", name, i)
+}
+
+func (sm *SessionManager) idleTimeout() time.Duration {
+	if sm.IdleTimeout <= 0 {
+		return DefaultSessionIdleTimeout
+	}
+	return sm.IdleTimeout
+}
+
+func (sm *SessionManager) maxCookieSize() int {
+	if sm.MaxCookieSize <= 0 {
+		return DefaultMaxCookieSize
+	}
+	return sm.MaxCookieSize
+}
+
+func (sm *SessionManager) maxChunks() int {
+	if sm.MaxChunks <= 0 {
+		return DefaultMaxChunks
+	}
+	return sm.MaxChunks
+}
+
+func (sm *SessionManager) now() time.Time {
+	if sm.Clock == nil {
+		return jwtkit.SystemClock.Now()
+	}
+	return sm.Clock.Now()
+}
@@ -0,0 +1,145 @@
+package restkit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilhammhdd/go-toolkit/jwtkit"
+	"github.com/ilhammhdd/go-toolkit/restkit"
+)
+
+func newSecretProvider(t *testing.T, secret string) jwtkit.KeyProvider {
+	kp, err := jwtkit.NewStaticKeyProvider([]byte(secret), nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return kp
+}
+
+// saveSession saves s and returns a request carrying the cookies the response set.
+func saveSession(t *testing.T, sm *restkit.SessionManager, previous *http.Request, s *restkit.Session) (*http.Request, *http.Response) {
+	w := httptest.NewRecorder()
+	err := sm.Save(w, previous, s)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge >= 0 {
+			r.AddCookie(cookie)
+		}
+	}
+	return r, w.Result()
+}
+
+func TestSessionManager(t *testing.T) {
+	clock := jwtkit.NewFrozenClock(time.Now())
+	oldKeys := newSecretProvider(t, "the previous secret long enough for HS256")
+	sm := &restkit.SessionManager{Keys: oldKeys, Clock: clock, IdleTimeout: 10 * time.Minute, MaxLifetime: time.Hour}
+
+	session := restkit.NewSession()
+	session.Values["user"] = "admin"
+	r, response := saveSession(t, sm, httptest.NewRequest(http.MethodGet, "/", nil), session)
+	cookie := response.Cookies()[0]
+	if !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Name != restkit.DefaultSessionCookieName {
+		t.Fatalf("unexpected cookie attributes got: %v", cookie)
+	}
+
+	loaded, err := sm.Load(r)
+	if err != nil || loaded.Values["user"] != "admin" || loaded.Id != session.Id {
+		t.Fatalf("expected the saved session got: %v %v", loaded, err)
+	}
+
+	sm.Keys, sm.PreviousKeys = newSecretProvider(t, "the current secret long enough for HS256"), oldKeys
+	if _, err = sm.Load(r); err != nil {
+		t.Fatalf("expected the session of the previous key to stay readable got: %v", err)
+	}
+	sm.PreviousKeys = nil
+	if _, err = sm.Load(r); !errors.Is(err, jwtkit.ErrInvalidSignature) {
+		t.Fatalf("expected %v got: %v", jwtkit.ErrInvalidSignature, err)
+	}
+
+	clock.Advance(9 * time.Minute)
+	r, _ = saveSession(t, sm, r, loaded)
+	clock.Advance(9 * time.Minute)
+	if _, err = sm.Load(r); err != nil {
+		t.Fatalf("expected save to slide the expiration got: %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err = sm.Load(r); !errors.Is(err, jwtkit.ErrExpired) {
+		t.Fatalf("expected %v got: %v", jwtkit.ErrExpired, err)
+	}
+
+	access, err := (&jwtkit.Issuer{Signer: mustProviderSigner(t, sm.Keys), Expiration: 60000, Clock: clock, TimeUnit: jwtkit.Seconds}).Issue("subject", nil)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: restkit.DefaultSessionCookieName, Value: string(access)})
+	if _, err = sm.Load(r); !errors.Is(err, jwtkit.ErrMalformed) {
+		t.Fatalf("expected an access token to be refused as session got: %v", err)
+	}
+	if _, err = sm.Load(httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, restkit.ErrNoSession) {
+		t.Fatalf("expected %v got: %v", restkit.ErrNoSession, err)
+	}
+}
+
+func mustProviderSigner(t *testing.T, kp jwtkit.KeyProvider) jwtkit.Signer {
+	signer, err := jwtkit.ProviderSigner(kp, "")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return signer
+}
+
+func TestSessionManagerEncryptedChunks(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	sm := &restkit.SessionManager{
+		Keys:           newSecretProvider(t, "a secret that is long enough for HS256"),
+		EncryptionKeys: jwtkit.NewKeyProviderFromSigner(privateKey),
+		MaxCookieSize:  1000,
+	}
+
+	session := restkit.NewSession()
+	session.Values["note"] = strings.Repeat("secret ", 200)
+	r, response := saveSession(t, sm, httptest.NewRequest(http.MethodGet, "/", nil), session)
+	if len(response.Cookies()) < 3 {
+		t.Fatalf("expected the session to be chunked got: %d cookies", len(response.Cookies()))
+	}
+	for _, cookie := range response.Cookies() {
+		if len(cookie.Name)+len(cookie.Value)+1 > sm.MaxCookieSize || strings.Contains(cookie.Value, "c2VjcmV0") {
+			t.Fatalf("expected small encrypted chunks got: %s", cookie.Name)
+		}
+	}
+	loaded, err := sm.Load(r)
+	if err != nil || loaded.Values["note"] != session.Values["note"] {
+		t.Fatalf("expected the chunked session got: %v", err)
+	}
+
+	session.Values["note"] = "short"
+	_, response = saveSession(t, sm, r, session)
+	expired := 0
+	for _, cookie := range response.Cookies() {
+		if cookie.MaxAge < 0 {
+			expired++
+		}
+	}
+	if expired < 2 {
+		t.Fatalf("expected the stale chunks to be expired got: %d", expired)
+	}
+
+	session.Values["note"] = strings.Repeat("x", 20000)
+	if err = sm.Save(httptest.NewRecorder(), r, session); !errors.Is(err, restkit.ErrSessionTooLarge) {
+		t.Fatalf("expected %v got: %v", restkit.ErrSessionTooLarge, err)
+	}
+}